@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//
+// ────────────────────────────── Email addresses ─────────────────────────────
+//
+
+const maxEmailLen = 254
+
+// normalizeEmail trims and lower-cases an address so Bob@x.com and bob@x.com
+// are the same account. Every lookup by email must go through it.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// validEmail is a deliberately loose format check: one "@", something on
+// both sides, a dot in the domain, no whitespace. Real proof of ownership
+// is the verification mail.
+func validEmail(email string) bool {
+	if len(email) > maxEmailLen || strings.ContainsAny(email, " \t\r\n") {
+		return false
+	}
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" || strings.Contains(domain, "@") {
+		return false
+	}
+	dot := strings.LastIndexByte(domain, '.')
+	return dot > 0 && dot < len(domain)-1
+}
+
+//
+// ───────────────────────────── Account management ───────────────────────────
+//
+
+type changePasswordReq struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+}
+
+// POST /auth/change-password
+// Verifies {oldPassword} (401 if wrong), stores {newPassword}, and revokes
+// every other access token of the user; the caller's token stays valid.
+// All refresh tokens are revoked too and a fresh one is returned.
+func (s *Server) changePassword(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+	jti, _ := jtiFromCtx(r)
+
+	var req changePasswordReq
+	if err := s.decodeValid(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	var hash string
+	if err := s.db.QueryRow(`SELECT password_hash FROM users WHERE id=$1`, uid).Scan(&hash); err != nil {
+		serverError(w, err)
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.OldPassword)) != nil {
+		writeError(w, http.StatusUnauthorized, codeInvalidCredentials, "invalid credentials")
+		return
+	}
+	newHash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), s.bcryptCost)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE users SET password_hash=$1 WHERE id=$2`, string(newHash), uid); err != nil {
+		serverError(w, err)
+		return
+	}
+	res, err := tx.Exec(`
+		UPDATE auth_tokens SET revoked_at=$3
+		WHERE user_id=$1 AND jti<>$2 AND revoked_at IS NULL AND expires_at > $3
+	`, uid, jti, s.clock.now())
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	revoked, _ := res.RowsAffected()
+	if _, err := tx.Exec(
+		`UPDATE refresh_tokens SET revoked_at=$2 WHERE user_id=$1 AND revoked_at IS NULL`, uid, s.clock.now(),
+	); err != nil {
+		serverError(w, err)
+		return
+	}
+	refresh, refreshExp, err := s.issueRefreshToken(tx, uid)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+
+	s.audit(r, uid, eventPasswordChanged, map[string]any{"revokedTokens": revoked})
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message":       "password changed",
+		"revokedTokens": revoked,
+		"refreshToken":  refresh,
+		"refreshExp":    refreshExp,
+	})
+}
+
+//
+// ─────────────────────────────── Password reset ─────────────────────────────
+//
+
+const resetTTL = time.Hour
+
+type forgotPasswordReq struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordReq struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"newPassword"`
+}
+
+// POST /auth/forgot-password
+// Mails a single-use reset link if {email} belongs to an account. Always
+// answers 200 so the endpoint can't be used to probe for accounts.
+func (s *Server) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotPasswordReq
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	var uid int64
+	req.Email = normalizeEmail(req.Email)
+	err := s.db.QueryRow(`SELECT id FROM users WHERE LOWER(email)=$1`, req.Email).Scan(&uid)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// Nothing to do; fall through to the generic answer.
+	case err != nil:
+		serverError(w, err)
+		return
+	default:
+		token, hash, err := newOpaqueToken()
+		if err != nil {
+			serverError(w, err)
+			return
+		}
+		if _, err := s.db.Exec(
+			`INSERT INTO password_resets(token_hash, user_id, expires_at) VALUES ($1,$2,$3)`,
+			hash, uid, s.clock.now().Add(resetTTL),
+		); err != nil {
+			serverError(w, err)
+			return
+		}
+		// Send in the background so response time doesn't reveal the account.
+		go s.sendPasswordReset(req.Email, token)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"message": "if the address is registered, a reset link is on its way",
+	})
+}
+
+func (s *Server) sendPasswordReset(email, token string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	link := s.publicURL + "/reset-password?token=" + url.QueryEscape(token)
+	body := "Someone asked to reset your TimeTrac password. If that was you, open:\n\n" + link +
+		"\n\nThe link expires in " + resetTTL.String() + ". Otherwise you can ignore this mail."
+	if err := s.mailer.Send(ctx, email, "Reset your password", body); err != nil {
+		log.Printf("send password reset to %s: %v", email, err)
+	}
+}
+
+// POST /auth/reset-password
+// Consumes {token} and stores {newPassword}. Every access and refresh token
+// of the account is revoked, and any lockout is cleared. Since the link was
+// delivered by mail, the address also counts as verified.
+func (s *Server) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordReq
+	if err := s.decodeValid(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+	newHash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), s.bcryptCost)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var uid int64
+	now := s.clock.now()
+	err = tx.QueryRow(`
+		UPDATE password_resets SET used_at=$2
+		WHERE token_hash=$1 AND used_at IS NULL AND expires_at > $2
+		RETURNING user_id
+	`, hashToken(req.Token), now).Scan(&uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusBadRequest, codeInvalidToken, "invalid or expired token")
+		return
+	}
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE users SET password_hash=$2, email_verified=true, failed_login_attempts=0, locked_until=NULL
+		WHERE id=$1
+	`, uid, string(newHash)); err != nil {
+		serverError(w, err)
+		return
+	}
+	// Sign out everywhere and burn any other outstanding reset links.
+	for _, q := range []string{
+		`UPDATE auth_tokens SET revoked_at=$2 WHERE user_id=$1 AND revoked_at IS NULL`,
+		`UPDATE refresh_tokens SET revoked_at=$2 WHERE user_id=$1 AND revoked_at IS NULL`,
+		`UPDATE password_resets SET used_at=$2 WHERE user_id=$1 AND used_at IS NULL`,
+	} {
+		if _, err := tx.Exec(q, uid, now); err != nil {
+			serverError(w, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+
+	s.audit(r, uid, eventPasswordReset, nil)
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "password reset; please log in again"})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	for in, want := range map[string]string{
+		"alice@example.com":       "alice@example.com",
+		"  Alice@Example.COM\t":   "alice@example.com",
+		"ALICE+tag@EXAMPLE.co.uk": "alice+tag@example.co.uk",
+	} {
+		if got := normalizeEmail(in); got != want {
+			t.Errorf("normalizeEmail(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRegisterMixedCaseDuplicate(t *testing.T) {
+	e := newTestEnv(t)
+
+	register := func(email string) int {
+		return e.do("POST", "/auth/register", "", map[string]string{
+			"email": email, "password": "password123",
+		}).Code
+	}
+	if c := register("Alice@Example.com"); c != http.StatusCreated {
+		t.Fatalf("first register = %d, want 201", c)
+	}
+	rec := e.do("POST", "/auth/register", "", map[string]string{
+		"email": " alice@EXAMPLE.com", "password": "password123",
+	})
+	wantStatus(t, rec, http.StatusConflict)
+	var body struct {
+		Error errorBody `json:"error"`
+	}
+	decode(t, rec, &body)
+	if body.Error.Code != codeEmailTaken {
+		t.Errorf("code = %q, want %q", body.Error.Code, codeEmailTaken)
+	}
+
+	// A legacy row stored before normalization still blocks its lower-case twin.
+	if _, err := e.s.db.Exec(`INSERT INTO users(email, password_hash) VALUES ('Bob@Example.com', 'x')`); err != nil {
+		t.Fatal(err)
+	}
+	if c := register("bob@example.com"); c != http.StatusConflict {
+		t.Errorf("register over legacy mixed-case row = %d, want 409", c)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+)
+
+//
+// ──────────────────────────────── Admin layer ───────────────────────────────
+//
+
+// adminOnly wraps authOnly and additionally requires the caller's role to be
+// "admin". The role is read from the DB on every request rather than trusted
+// from the token, so demoting an admin takes effect immediately.
+func (s *Server) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return s.authOnly(func(w http.ResponseWriter, r *http.Request) {
+		uid, _ := userIDFromCtx(r)
+
+		var role string
+		if err := s.db.QueryRow(`SELECT role FROM users WHERE id=$1`, uid).Scan(&role); err != nil {
+			serverError(w, err)
+			return
+		}
+		if role != "admin" {
+			writeError(w, http.StatusForbidden, codeForbidden, "admin only")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+//
+// ──────────────────────────────── User listing ──────────────────────────────
+//
+
+// AdminUser is one row of the admin user list.
+type AdminUser struct {
+	ID            int64      `json:"id"`
+	Email         string     `json:"email"`
+	Role          string     `json:"role"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	EmailVerified bool       `json:"emailVerified"`
+	SuspendedAt   *time.Time `json:"suspendedAt,omitempty"`
+}
+
+// AdminUserPage is one page of GET /api/admin/users.
+type AdminUserPage struct {
+	Items      []AdminUser `json:"items"`
+	NextOffset *int        `json:"nextOffset,omitempty"`
+	Total      int         `json:"total"`
+}
+
+// GET /api/admin/users[?limit=&offset=]
+// Lists every account, oldest first, as {items, nextOffset, total} (same
+// paging as /api/time/sessions).
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	pg, err := parsePage(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	out := AdminUserPage{Items: []AdminUser{}}
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&out.Total); err != nil {
+		serverError(w, err)
+		return
+	}
+	rows, err := s.db.Query(`
+		SELECT id, email, role, created_at, email_verified, suspended_at
+		FROM users
+		ORDER BY id ASC
+		LIMIT $1 OFFSET $2
+	`, pg.Limit, pg.Offset)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u AdminUser
+		if err := rows.Scan(&u.ID, &u.Email, &u.Role, &u.CreatedAt, &u.EmailVerified, &u.SuspendedAt); err != nil {
+			serverError(w, err)
+			return
+		}
+		out.Items = append(out.Items, u)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, err)
+		return
+	}
+	if next := pg.Offset + len(out.Items); next < out.Total {
+		out.NextOffset = &next
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+//
+// ─────────────────────────────── Moderation API ─────────────────────────────
+//
+
+// POST /api/admin/users/{id}/suspend
+// Blocks login (403) and rejects the user's existing tokens. Data is kept.
+func (s *Server) suspendUser(w http.ResponseWriter, r *http.Request) {
+	s.setSuspended(w, r, true)
+}
+
+// POST /api/admin/users/{id}/unsuspend
+// Reverses a suspension; the user can log in again.
+func (s *Server) unsuspendUser(w http.ResponseWriter, r *http.Request) {
+	s.setSuspended(w, r, false)
+}
+
+func (s *Server) setSuspended(w http.ResponseWriter, r *http.Request, suspend bool) {
+	target, err := strToInt64(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "bad user id")
+		return
+	}
+	if self, _ := userIDFromCtx(r); suspend && self == target {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "cannot suspend yourself")
+		return
+	}
+
+	var at sql.NullTime
+	if suspend {
+		at = sql.NullTime{Time: s.clock.now(), Valid: true}
+	}
+	// COALESCE keeps the original timestamp if the user is already suspended.
+	err = s.db.QueryRow(`
+		UPDATE users
+		SET suspended_at = CASE WHEN $2::timestamptz IS NULL THEN NULL ELSE COALESCE(suspended_at, $2) END
+		WHERE id=$1
+		RETURNING suspended_at
+	`, target, at).Scan(&at)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, codeUserNotFound, "user not found")
+		return
+	}
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+
+	event := eventUserUnsuspended
+	if suspend {
+		event = eventUserSuspended
+	}
+	self, _ := userIDFromCtx(r)
+	s.audit(r, target, event, map[string]any{"by": self})
+
+	out := map[string]any{"id": target, "suspended": at.Valid}
+	if at.Valid {
+		out["suspendedAt"] = at.Time
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//
+// ───────────────────────────────── API keys ─────────────────────────────────
+//
+
+// API keys look like "ak_<43 url-safe chars>" and are sent as
+// "Authorization: Bearer ak_..."; authOnly accepts them wherever it accepts
+// an access token. A key acts as its owner until revoked.
+
+const (
+	apiKeyPrefix    = "ak_"
+	apiKeyPrefixLen = 8 // characters after "ak_" kept in the clear
+	maxAPIKeyName   = 100
+)
+
+// APIKey is a key's metadata; Key is set only in the create response.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	Key        string     `json:"key,omitempty"`
+}
+
+type apiKeyReq struct {
+	Name string `json:"name"`
+}
+
+// apiKeyUser resolves a presented key to its owner and bumps last_used_at.
+// sql.ErrNoRows means unknown or revoked.
+func (s *Server) apiKeyUser(key string) (uid int64, suspended bool, err error) {
+	var suspendedAt sql.NullTime
+	err = s.db.QueryRow(`
+		UPDATE api_keys k SET last_used_at=$2
+		FROM users u
+		WHERE k.hash=$1 AND k.revoked_at IS NULL AND u.id = k.user_id
+		RETURNING k.user_id, u.suspended_at
+	`, hashToken(key), s.clock.now()).Scan(&uid, &suspendedAt)
+	return uid, suspendedAt.Valid, err
+}
+
+// POST /auth/api-keys
+// {name}: creates a key and returns it once, in plain text, as "key"; only
+// its hash is stored. 201 + Location.
+func (s *Server) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	var req apiKeyReq
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxAPIKeyName {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "name must be 1-100 characters")
+		return
+	}
+
+	token, _, err := newOpaqueToken()
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	k := APIKey{Name: req.Name, Prefix: apiKeyPrefix + token[:apiKeyPrefixLen], Key: apiKeyPrefix + token}
+	if err := s.db.QueryRow(
+		`INSERT INTO api_keys(user_id, prefix, hash, name) VALUES ($1,$2,$3,$4) RETURNING id, created_at`,
+		uid, k.Prefix, hashToken(k.Key), k.Name,
+	).Scan(&k.ID, &k.CreatedAt); err != nil {
+		serverError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/auth/api-keys/"+int64ToStr(k.ID))
+	writeJSON(w, http.StatusCreated, k)
+}
+
+// GET /auth/api-keys
+// Lists the caller's keys, newest first, revoked ones included.
+func (s *Server) listAPIKeys(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	rows, err := s.db.Query(`
+		SELECT id, name, prefix, created_at, last_used_at, revoked_at
+		FROM api_keys
+		WHERE user_id=$1
+		ORDER BY created_at DESC, id DESC
+	`, uid)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	out := []APIKey{}
+	for rows.Next() {
+		var k APIKey
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+			serverError(w, err)
+			return
+		}
+		out = append(out, k)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// DELETE /auth/api-keys/{id}
+// Revokes the key, 204. Unknown, already revoked and other users' keys are
+// 404.
+func (s *Server) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+	id, err := strToInt64(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "bad api key id")
+		return
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE api_keys SET revoked_at=$3 WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL`, id, uid, s.clock.now(),
+	)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusNotFound, codeAPIKeyNotFound, "api key not found")
+		return
+	}
+	s.audit(r, uid, eventAPIKeyRevoked, map[string]any{"apiKeyId": id})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authAPIKey is authOnly's path for "Bearer ak_..." credentials.
+func (s *Server) authAPIKey(w http.ResponseWriter, r *http.Request, key string, next http.HandlerFunc) {
+	uid, suspended, err := s.apiKeyUser(key)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusUnauthorized, codeInvalidToken, "invalid api key")
+		return
+	}
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if suspended {
+		writeError(w, http.StatusForbidden, codeAccountSuspended, "account suspended")
+		return
+	}
+	next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxUserID, uid)))
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// ───────────────────────────────── Audit log ────────────────────────────────
+//
+
+// Audit event names; stored as-is, so they must not change once shipped.
+const (
+	eventLoginSuccess    = "login_success"
+	eventLoginFailure    = "login_failure" // meta.reason: unknown_email, password, totp, locked, suspended, unverified
+	eventLogout          = "logout"
+	eventLogoutAll       = "logout_all"
+	eventPasswordChanged = "password_changed"
+	eventPasswordReset   = "password_reset"
+	eventTOTPEnabled     = "totp_enabled"
+	eventTOTPDisabled    = "totp_disabled"
+	eventAPIKeyRevoked   = "api_key_revoked"
+	eventAccountDeleted  = "account_deleted"
+	eventUserSuspended   = "user_suspended" // by an admin; meta.by is the admin's id
+	eventUserUnsuspended = "user_unsuspended"
+	eventProjectTransfer = "project_transfer" // meta: from, to, moved, sourceDeleted
+)
+
+const (
+	auditQueueSize        = 1024 // events waiting for runAuditWriter
+	maxAuditUserAgentSize = 512
+)
+
+// auditEvent is one queued audit_log row.
+type auditEvent struct {
+	userID    sql.NullInt64
+	event     string
+	ip        string
+	userAgent string
+	at        time.Time
+	meta      map[string]any
+}
+
+// audit records event for uid (0 = unknown) with the request's client IP
+// and user agent. It never blocks: events go through a queue drained by
+// runAuditWriter, and are dropped (and logged) when the queue is full, so a
+// slow database can't hold up logins.
+func (s *Server) audit(r *http.Request, uid int64, event string, meta map[string]any) {
+	ev := auditEvent{
+		userID:    sql.NullInt64{Int64: uid, Valid: uid != 0},
+		event:     event,
+		ip:        clientIP(r),
+		userAgent: r.UserAgent(),
+		at:        s.clock.now(),
+		meta:      meta,
+	}
+	if len(ev.userAgent) > maxAuditUserAgentSize {
+		ev.userAgent = ev.userAgent[:maxAuditUserAgentSize]
+	}
+	select {
+	case s.auditQueue <- ev:
+	default:
+		log.Printf("audit queue full, dropped %s for user %d", event, uid)
+	}
+}
+
+// runAuditWriter stores queued audit events until ctx is done. Events of
+// requests still finishing after that are written by drainAudit.
+func (s *Server) runAuditWriter(ctx context.Context) {
+	for {
+		select {
+		case ev := <-s.auditQueue:
+			s.writeAudit(ctx, ev)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// drainAudit writes whatever is still queued; main calls it after the HTTP
+// server has shut down.
+func (s *Server) drainAudit(ctx context.Context) {
+	for {
+		select {
+		case ev := <-s.auditQueue:
+			s.writeAudit(ctx, ev)
+		default:
+			return
+		}
+	}
+}
+
+func (s *Server) writeAudit(ctx context.Context, ev auditEvent) {
+	meta := []byte("{}")
+	if ev.meta != nil {
+		meta, _ = json.Marshal(ev.meta)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO audit_log(user_id, event, ip, user_agent, created_at, meta)
+		VALUES ($1,$2,$3,$4,$5,$6)
+	`, ev.userID, ev.event, ev.ip, ev.userAgent, ev.at, meta); err != nil {
+		log.Printf("audit %s for user %d: %v", ev.event, ev.userID.Int64, err)
+	}
+}
+
+//
+// ──────────────────────────────── Audit listing ─────────────────────────────
+//
+
+// AuditEntry is one row of the audit log.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	UserID    *int64          `json:"userId"`
+	Event     string          `json:"event"`
+	IP        string          `json:"ip"`
+	UserAgent string          `json:"userAgent"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Meta      json.RawMessage `json:"meta"`
+}
+
+// AuditPage is one page of an audit listing, newest first.
+type AuditPage struct {
+	Items      []AuditEntry `json:"items"`
+	NextOffset *int         `json:"nextOffset,omitempty"`
+	Total      int          `json:"total"`
+}
+
+// GET /auth/audit[?event=&limit=&offset=]
+// The caller's own audit events as {items, nextOffset, total}, newest first.
+func (s *Server) myAudit(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+	s.listAudit(w, r, uid)
+}
+
+// GET /api/admin/audit[?userId=&event=&limit=&offset=]
+// Every user's audit events (or one user's with userId), like /auth/audit.
+func (s *Server) adminAudit(w http.ResponseWriter, r *http.Request) {
+	var uid int64
+	if v := r.URL.Query().Get("userId"); v != "" {
+		var err error
+		if uid, err = strToInt64(v); err != nil || uid <= 0 {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "bad userId")
+			return
+		}
+	}
+	s.listAudit(w, r, uid)
+}
+
+// listAudit writes one page of audit_log, restricted to uid unless it is 0.
+func (s *Server) listAudit(w http.ResponseWriter, r *http.Request, uid int64) {
+	q := r.URL.Query()
+	pg, err := parsePage(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	var conds []string
+	var args []any
+	if uid != 0 {
+		args = append(args, uid)
+		conds = append(conds, "user_id=$"+strconv.Itoa(len(args)))
+	}
+	if ev := q.Get("event"); ev != "" {
+		args = append(args, ev)
+		conds = append(conds, "event=$"+strconv.Itoa(len(args)))
+	}
+	where := "TRUE"
+	if len(conds) > 0 {
+		where = strings.Join(conds, " AND ")
+	}
+
+	out := AuditPage{Items: []AuditEntry{}}
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE `+where, args...).Scan(&out.Total); err != nil {
+		serverError(w, err)
+		return
+	}
+	n := len(args)
+	rows, err := s.db.Query(`
+		SELECT id, user_id, event, ip, user_agent, created_at, meta
+		FROM audit_log
+		WHERE `+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $`+strconv.Itoa(n+1)+` OFFSET $`+strconv.Itoa(n+2),
+		append(args, pg.Limit, pg.Offset)...)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e AuditEntry
+		var meta []byte
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Event, &e.IP, &e.UserAgent, &e.CreatedAt, &meta); err != nil {
+			serverError(w, err)
+			return
+		}
+		e.Meta = meta
+		out.Items = append(out.Items, e)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, err)
+		return
+	}
+	if next := pg.Offset + len(out.Items); next < out.Total {
+		out.NextOffset = &next
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+//
+// ───────────────────────────────── Client IPs ───────────────────────────────
+//
+
+// realIP resolves the client address once per request (see clientIP).
+// Forwarding headers are only believed when the immediate peer is one of
+// the trusted proxies (TRUSTED_PROXIES); anyone else could simply send a
+// made-up X-Forwarded-For to dodge the rate limits.
+func realIP(trusted []netip.Prefix, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := resolveClientIP(r, trusted)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxClientIP, ip)))
+	})
+}
+
+// clientIP is the address of the real client, for rate limiting and logs.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(ctxClientIP).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// resolveClientIP walks X-Forwarded-For from the right, skipping trusted
+// proxies; the first other address is the client (entries further left
+// were written by the client itself and prove nothing). X-Real-IP is used
+// when there is no X-Forwarded-For.
+func resolveClientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer := remoteIP(r)
+	if !isTrusted(peer, trusted) {
+		return peer
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break // garbage; don't look past it
+		}
+		if !isTrusted(hop, trusted) {
+			return hop
+		}
+		peer = hop
+	}
+	if v := strings.TrimSpace(r.Header.Get("X-Real-IP")); r.Header.Get("X-Forwarded-For") == "" && v != "" {
+		if _, err := netip.ParseAddr(v); err == nil {
+			return v
+		}
+	}
+	return peer
+}
+
+func isTrusted(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP is the immediate peer address without the port.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// parsePrefixes reads TRUSTED_PROXIES entries: CIDRs or single addresses.
+func parsePrefixes(list []string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, v := range list {
+		if p, err := netip.ParsePrefix(v); err == nil {
+			out = append(out, p.Masked())
+			continue
+		}
+		a, err := netip.ParseAddr(v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, netip.PrefixFrom(a.Unmap(), a.Unmap().BitLen()))
+	}
+	return out, nil
+}
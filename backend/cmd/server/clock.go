@@ -0,0 +1,23 @@
+package main
+
+import "time"
+
+//
+// ─────────────────────────────────── Clock ──────────────────────────────────
+//
+
+// Clock is where the server reads the time: session starts and stops,
+// day/week boundaries, token issue and expiry, lockouts, rate limits. Tests
+// inject a fake to get exact durations; production uses realClock.
+//
+// Queries take the time as a parameter instead of calling SQL NOW(), both
+// for expiry checks and for revoked_at/used_at stamps, so a test that moves
+// the clock sees consistent rows. Only column defaults (created_at) stay on
+// the database clock.
+type Clock interface {
+	now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) now() time.Time { return time.Now() }
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//
+// ─────────────────────────────── Compression ────────────────────────────────
+//
+
+// gzipMinSize is the smallest body worth compressing; below it the gzip
+// header and CPU cost more than the bytes saved.
+const gzipMinSize = 1024
+
+var gzipPool = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+
+// compress gzips responses for clients that accept it. It sits inside
+// logging(), so the logged byte count is what actually went over the wire.
+// The first gzipMinSize bytes are held back to decide; smaller bodies are
+// sent as-is.
+func compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipWriter{ResponseWriter: w}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+// (explicitly or via "*"), honoring q=0 as a refusal.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(v, 64); err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipWriter buffers the start of the body until it knows whether to
+// compress, then either streams through a pooled gzip.Writer or flushes the
+// buffer untouched.
+type gzipWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (g *gzipWriter) WriteHeader(code int) {
+	switch {
+	case g.decided:
+		g.ResponseWriter.WriteHeader(code) // let net/http flag the duplicate
+	case g.status == 0:
+		g.status = code
+	}
+}
+
+func (g *gzipWriter) Write(b []byte) (int, error) {
+	if g.decided {
+		if g.gz != nil {
+			return g.gz.Write(b)
+		}
+		return g.ResponseWriter.Write(b)
+	}
+	g.buf = append(g.buf, b...)
+	if len(g.buf) >= gzipMinSize {
+		if err := g.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// decide commits to compressing (if allowed for this response) or not, and
+// writes out the buffered header and body.
+func (g *gzipWriter) decide(large bool) error {
+	g.decided = true
+	h := g.Header()
+	status := g.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if large && h.Get("Content-Encoding") == "" &&
+		status != http.StatusNoContent && status != http.StatusNotModified {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		g.gz = gzipPool.Get().(*gzip.Writer)
+		g.gz.Reset(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(status)
+	buf := g.buf
+	g.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if g.gz != nil {
+		_, err = g.gz.Write(buf)
+	} else {
+		_, err = g.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush sends what's buffered so far; a streaming response that flushes is
+// assumed to be large and gets compressed.
+func (g *gzipWriter) Flush() {
+	if !g.decided {
+		g.decide(true)
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	http.NewResponseController(g.ResponseWriter).Flush()
+}
+
+// close finishes the response: small bodies go out uncompressed, and the
+// gzip stream (if any) is terminated and returned to the pool.
+func (g *gzipWriter) close() {
+	if !g.decided {
+		if g.status == 0 && len(g.buf) == 0 {
+			return // handler wrote nothing; let net/http send its default 200
+		}
+		g.decide(false)
+	}
+	if g.gz != nil {
+		g.gz.Close()
+		gzipPool.Put(g.gz)
+		g.gz = nil
+	}
+}
+
+// Unwrap lets http.ResponseController reach the real writer.
+func (g *gzipWriter) Unwrap() http.ResponseWriter { return g.ResponseWriter }
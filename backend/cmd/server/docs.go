@@ -0,0 +1,46 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//
+// ──────────────────────────────── API reference ─────────────────────────────
+//
+
+// openAPISpec is the hand-written OpenAPI 3 document for every route in
+// main(). Update it together with the handler or request/response type
+// (loginReq, registerReq, Session, ...) it describes.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// GET /openapi.json
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// swaggerUI renders /openapi.json with Swagger UI from a CDN, so nothing
+// extra has to be vendored into the binary.
+const swaggerUI = `<!doctype html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>timetrac API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`
+
+// GET /docs
+func serveDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUI))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+)
+
+//
+// ─────────────────────────────── Error responses ────────────────────────────
+//
+
+// Stable error codes. Clients branch on these, never on the message text,
+// so a code must not change once shipped.
+const (
+	codeBadJSON             = "bad_json"
+	codeInvalidRequest      = "invalid_request"
+	codeMethodNotAllowed    = "method_not_allowed"
+	codeNotFound            = "not_found"
+	codeBodyTooLarge        = "body_too_large"
+	codeInternal            = "internal_error"
+	codeRateLimited         = "rate_limited"
+	codeForbidden           = "forbidden"
+	codeUnauthorized        = "unauthorized"
+	codeInvalidToken        = "invalid_token"
+	codeInvalidCredentials  = "invalid_credentials"
+	codeInvalidEmail        = "invalid_email"
+	codeEmailTaken          = "email_taken"
+	codeEmailNotVerified    = "email_not_verified"
+	codeWeakPassword        = "weak_password" // retired: now a validation_failed field error
+	codeValidationFailed    = "validation_failed"
+	codeAccountLocked       = "account_locked"
+	codeAccountSuspended    = "account_suspended"
+	codeUserNotFound        = "user_not_found"
+	codeProjectNotFound     = "project_not_found"
+	codeAPIKeyNotFound      = "api_key_not_found"
+	codeSessionNotFound     = "session_not_found"
+	codeNoOpenSession       = "no_open_session"
+	codeSessionRunning      = "session_already_running"
+	codeSessionOverlap      = "session_overlap"
+	codeSessionPaused       = "session_paused"
+	codeSessionNotPaused    = "session_not_paused"
+	codeTimeInFuture        = "time_in_future"
+	codeVersionConflict     = "session_version_conflict"
+	codeConfirmationMissing = "confirmation_required"
+	codeImportRejected      = "import_rejected"
+	codeTOTPRequired        = "totp_required"
+	codeInvalidTOTP         = "invalid_totp_code"
+	codeTOTPEnabled         = "totp_already_enabled"
+	codeTOTPUnavailable     = "totp_unavailable"
+
+	codeIdempotencyInProgress = "idempotency_in_progress"
+	codeIdempotencyKeyReused  = "idempotency_key_reused"
+)
+
+// errorBody is the payload of every error response:
+// {"error": {"code": "invalid_credentials", "message": "..."}}.
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+// writeError emits a JSON error. message is for humans; code is the contract.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorDetails(w, status, code, message, nil)
+}
+
+// writeErrorDetails is writeError plus machine-readable context (e.g. the
+// conflicting session id, or the failed password rules).
+func writeErrorDetails(w http.ResponseWriter, status int, code, message string, details any) {
+	writeJSON(w, status, map[string]errorBody{"error": {Code: code, Message: message, Details: details}})
+}
+
+// serverError logs err and answers 500 without leaking its text.
+func serverError(w http.ResponseWriter, err error) {
+	log.Printf("internal error: %v", err)
+	writeError(w, http.StatusInternalServerError, codeInternal, "internal server error")
+}
+
+// apiError is an error that already knows how it should be reported, for
+// helpers that can fail both on bad input and on the database.
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string { return e.message }
+
+func badRequest(code, message string) error {
+	return &apiError{http.StatusBadRequest, code, message}
+}
+
+// writeErr reports an *apiError as itself, a *validationError as 422 (see
+// validate.go) and anything else as a 500.
+func writeErr(w http.ResponseWriter, err error) {
+	if writeValidation(w, err) {
+		return
+	}
+	var ae *apiError
+	if errors.As(err, &ae) {
+		writeError(w, ae.status, ae.code, ae.message)
+		return
+	}
+	serverError(w, err)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// ──────────────────────────────── CSV export ────────────────────────────────
+//
+
+// csvFlushEvery bounds how many rows sit in the writer's buffer.
+const csvFlushEvery = 100
+
+// GET /api/time/export.csv?from=&to=[&tz=&rounding=&roundTo=]
+// Streams the user's sessions in the range as CSV
+// (id, start, end, duration, project, note), oldest first. Times are RFC3339
+// in the user's zone; duration is h:mm:ss and empty for a running session.
+// With rounding/roundTo a rounded_minutes column is appended (rounding.go);
+// duration stays the raw time.
+// Rows are written as they are read, so large ranges don't sit in memory.
+func (s *Server) exportCSV(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	loc, err := s.userLocation(r, uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	rng, err := parseDateRange(r.URL.Query(), loc)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	rd, err := parseRounding(r.URL.Query(), s.roundTo)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	rows, err := s.db.QueryContext(r.Context(), `
+		SELECT s.id, s.start_time, s.end_time, COALESCE(s.duration_seconds, s.duration_minutes * 60),
+		       COALESCE(p.name, ''), s.note
+		FROM sessions s
+		LEFT JOIN projects p ON p.id = s.project_id
+		WHERE s.user_id=$1 AND s.start_time >= $2 AND s.start_time < $3 AND s.deleted_at IS NULL
+		ORDER BY s.start_time ASC, s.id ASC
+	`, uid, rng.From, rng.To)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	last := rng.To.Add(-time.Nanosecond).In(loc)
+	filename := fmt.Sprintf("timetrac-%s_%s.csv", rng.From.In(loc).Format(time.DateOnly), last.Format(time.DateOnly))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	cw := csv.NewWriter(w)
+	header := []string{"id", "start", "end", "duration", "project", "note"}
+	if rd != nil {
+		header = append(header, "rounded_minutes")
+	}
+	_ = cw.Write(header)
+	for n := 1; rows.Next(); n++ {
+		var (
+			id      int64
+			start   time.Time
+			end     sql.NullTime
+			secs    sql.NullInt64
+			project string
+			note    string
+		)
+		if err := rows.Scan(&id, &start, &end, &secs, &project, &note); err != nil {
+			// Headers are gone already; all we can do is cut the stream short.
+			log.Printf("export csv user %d: %v", uid, err)
+			return
+		}
+		rec := []string{strconv.FormatInt(id, 10), start.In(loc).Format(time.RFC3339), "", "", csvSafe(project), csvSafe(note)}
+		if end.Valid {
+			rec[2] = end.Time.In(loc).Format(time.RFC3339)
+		}
+		if secs.Valid {
+			rec[3] = formatHMS(secs.Int64)
+		}
+		if rd != nil {
+			m := ""
+			if secs.Valid {
+				m = strconv.FormatInt(rd.minutes(secs.Int64), 10)
+			}
+			rec = append(rec, m)
+		}
+		if err := cw.Write(rec); err != nil {
+			return // client went away
+		}
+		if n%csvFlushEvery == 0 {
+			cw.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("export csv user %d: %v", uid, err)
+	}
+	cw.Flush()
+}
+
+// formatHMS renders seconds as h:mm:ss, which spreadsheets read as a duration.
+func formatHMS(secs int64) string {
+	return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+}
+
+// csvSafe defuses spreadsheet formula injection: a cell starting with
+// = + - @ (or a control char) is prefixed with an apostrophe.
+func csvSafe(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
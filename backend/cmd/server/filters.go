@@ -0,0 +1,333 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// ──────────────────────────── Session list filters ──────────────────────────
+//
+
+// sessionFilter holds the optional query-string filters of listSessions.
+//
+//	from / to                date range (see parseDateRange); default today (user's zone)
+//	minMinutes / maxMinutes  duration range (inclusive) of finished sessions
+//	includeOpen=true         also match open sessions, using live elapsed time minus pauses
+//	tag                      sessions carrying this tag
+//	status=open|closed|all   running or finished sessions only; default all
+//	includeArchived=true     also list archived sessions (see archiveSessions)
+type sessionFilter struct {
+	Range       dateRange
+	Tag         string
+	MinMinutes  *int
+	MaxMinutes  *int
+	IncludeOpen bool
+	Status      string // "open", "closed" or "" (all)
+
+	IncludeArchived bool
+}
+
+// parseSessionFilter validates the query string; errors are client errors (400).
+// Days are taken in loc.
+func parseSessionFilter(q url.Values, now time.Time, loc *time.Location) (sessionFilter, error) {
+	f := sessionFilter{Range: today(now, loc)}
+	var err error
+
+	if q.Has("from") || q.Has("to") {
+		if f.Range, err = parseDateRange(q, loc); err != nil {
+			return f, err
+		}
+	}
+	if f.MinMinutes, err = parseOptionalMinutes(q, "minMinutes"); err != nil {
+		return f, err
+	}
+	if f.MaxMinutes, err = parseOptionalMinutes(q, "maxMinutes"); err != nil {
+		return f, err
+	}
+	if f.MinMinutes != nil && f.MaxMinutes != nil && *f.MinMinutes > *f.MaxMinutes {
+		return f, errors.New("minMinutes must be <= maxMinutes")
+	}
+	f.IncludeOpen = q.Get("includeOpen") == "true"
+	f.IncludeArchived = q.Get("includeArchived") == "true"
+	f.Tag = strings.TrimSpace(q.Get("tag"))
+	switch st := q.Get("status"); st {
+	case "", "all":
+	case "open", "closed":
+		f.Status = st
+	default:
+		return f, errors.New("status must be open, closed or all")
+	}
+	return f, nil
+}
+
+// parseOptionalMinutes reads a non-negative integer param; nil when absent.
+func parseOptionalMinutes(q url.Values, key string) (*int, error) {
+	v := q.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return nil, errors.New(key + " must be a non-negative integer")
+	}
+	return &n, nil
+}
+
+// where builds the SQL WHERE clause (without the keyword) and its args.
+// now is used for the live elapsed time of open sessions, which excludes
+// their pauses as on stop and in /api/time/current.
+func (f sessionFilter) where(uid int64, now time.Time) (string, []any) {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conds := []string{
+		"user_id=" + arg(uid), "deleted_at IS NULL",
+		"start_time >= " + arg(f.Range.From), "start_time < " + arg(f.Range.To),
+	}
+
+	if !f.IncludeArchived {
+		conds = append(conds, "archived_at IS NULL")
+	}
+
+	if f.Tag != "" {
+		conds = append(conds, "tags @> ARRAY["+arg(f.Tag)+"]::text[]")
+	}
+
+	switch f.Status {
+	case "open":
+		conds = append(conds, "end_time IS NULL")
+	case "closed":
+		conds = append(conds, "end_time IS NOT NULL")
+	}
+
+	if f.MinMinutes != nil || f.MaxMinutes != nil {
+		dur := "duration_minutes"
+		if f.IncludeOpen {
+			at := arg(now)
+			dur = "COALESCE(duration_minutes, FLOOR(GREATEST(0, EXTRACT(EPOCH FROM (" + at + " - start_time)) - " +
+				pausedSQL(at) + ") / 60))"
+		} else {
+			conds = append(conds, "end_time IS NOT NULL")
+		}
+		if f.MinMinutes != nil {
+			conds = append(conds, dur+" >= "+arg(*f.MinMinutes))
+		}
+		if f.MaxMinutes != nil {
+			conds = append(conds, dur+" <= "+arg(*f.MaxMinutes))
+		}
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+//
+// ──────────────────────────────── Date ranges ───────────────────────────────
+//
+
+// maxRangeDays caps ?from=&to= so a typo can't trigger a full-table scan.
+const maxRangeDays = 366
+
+// dateRange is a half-open interval [From, To). parseDateRange turns the
+// inclusive ?to= of the API into the exclusive To.
+type dateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// parseDateRange reads ?from=&to=, each either YYYY-MM-DD or RFC3339; both
+// ends are inclusive. Plain dates are whole days in loc, so to=2024-05-31
+// includes that day; an RFC3339 to includes that instant, so from == to
+// matches a session starting exactly then.
+func parseDateRange(q url.Values, loc *time.Location) (dateRange, error) {
+	var rng dateRange
+	from, to := q.Get("from"), q.Get("to")
+	if from == "" || to == "" {
+		return rng, errors.New("from and to are required")
+	}
+
+	var err error
+	if rng.From, _, err = parseDateOrTime(from, loc); err != nil {
+		return rng, errors.New("from: " + err.Error())
+	}
+	var dateOnly bool
+	if rng.To, dateOnly, err = parseDateOrTime(to, loc); err != nil {
+		return rng, errors.New("to: " + err.Error())
+	}
+	if dateOnly {
+		rng.To = rng.To.AddDate(0, 0, 1)
+	} else {
+		// Postgres keeps microseconds: "< to + 1µs" is "<= to".
+		rng.To = rng.To.Truncate(time.Microsecond).Add(time.Microsecond)
+	}
+
+	if rng.To.Before(rng.From) {
+		return rng, errors.New("from must be <= to")
+	}
+	if rng.To.Sub(rng.From) > maxRangeDays*24*time.Hour {
+		return rng, errors.New("range too large (max " + strconv.Itoa(maxRangeDays) + " days)")
+	}
+	return rng, nil
+}
+
+// parseDateOrTime accepts YYYY-MM-DD (midnight in loc, reported as dateOnly)
+// or RFC3339.
+func parseDateOrTime(v string, loc *time.Location) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.ParseInLocation(time.DateOnly, v, loc); err == nil {
+		return t, true, nil
+	}
+	if t, err = time.Parse(time.RFC3339, v); err == nil {
+		return t, false, nil
+	}
+	return t, false, errors.New("expected YYYY-MM-DD or RFC3339")
+}
+
+//
+// ──────────────────────────────── Pagination ────────────────────────────────
+//
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// page is a limit/offset window; limit is capped at maxPageLimit. After,
+// when set, replaces the offset with a keyset position (see sessionCursor).
+type page struct {
+	Limit  int
+	Offset int
+	After  *sessionCursor
+}
+
+// listETag is the weak validator of one page of a session list. Any insert,
+// edit or soft delete in the filtered set changes the count or bumps
+// MAX(updated_at); the WHERE clause and its resolved args (so "today"
+// rolls over at midnight, and arg-less filters like status still count) and
+// the page and body shape (envelope or bare array) are mixed in too.
+func listETag(where string, args []any, pg page, envelope bool, count int, lastUpdate time.Time) string {
+	after := ""
+	if pg.After != nil {
+		after = pg.After.String()
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%v|%d|%d|%s|%t|%d|%d", where, args, pg.Limit, pg.Offset, after, envelope, count, lastUpdate.UnixNano()))
+	return `W/"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// etagMatch reports whether an If-None-Match header lists etag (weak
+// comparison, so the W/ prefix is ignored) or is "*".
+func etagMatch(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePage reads ?limit=&offset= (defaults 50 / 0).
+func parsePage(q url.Values) (page, error) {
+	pg := page{Limit: defaultPageLimit}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return pg, errors.New("limit must be a positive integer")
+		}
+		pg.Limit = min(n, maxPageLimit)
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return pg, errors.New("offset must be a non-negative integer")
+		}
+		pg.Offset = n
+	}
+	return pg, nil
+}
+
+// pageLinks builds an RFC 8288 Link header for a list page from the request
+// URL u, keeping every other query parameter. In offset mode next and prev
+// step by the limit; in cursor mode (pg.After set) next follows nextCursor
+// and there is no prev, since a keyset only runs forward. "" if neither
+// applies.
+func pageLinks(u *url.URL, pg page, nextOffset *int, nextCursor string) string {
+	link := func(rel string, set func(url.Values)) string {
+		q := u.Query()
+		set(q)
+		return `<` + u.Path + `?` + q.Encode() + `>; rel="` + rel + `"`
+	}
+	var links []string
+	switch {
+	case pg.After != nil && nextCursor != "":
+		links = append(links, link("next", func(q url.Values) { q.Set("after", nextCursor) }))
+	case pg.After == nil:
+		if nextOffset != nil {
+			links = append(links, link("next", func(q url.Values) { q.Set("offset", strconv.Itoa(*nextOffset)) }))
+		}
+		if pg.Offset > 0 {
+			prev := max(0, pg.Offset-pg.Limit)
+			links = append(links, link("prev", func(q url.Values) { q.Set("offset", strconv.Itoa(prev)) }))
+		}
+	}
+	return strings.Join(links, ", ")
+}
+
+// sessionCursor is a keyset position in the session list: the (start_time,
+// id) of the last row a client has seen. Unlike an offset it stays put when
+// rows are inserted ahead of it, and Postgres seeks straight to it through
+// the (user_id, start_time) index instead of counting past skipped rows.
+type sessionCursor struct {
+	Start time.Time
+	ID    int64
+}
+
+// cursorAfter is the cursor that resumes right after ss.
+func cursorAfter(ss Session) *sessionCursor {
+	return &sessionCursor{Start: ss.StartTime, ID: ss.ID}
+}
+
+// String encodes the cursor as opaque URL-safe base64 for ?after=.
+func (c sessionCursor) String() string {
+	raw := c.Start.UTC().Format(time.RFC3339Nano) + "|" + int64ToStr(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// parseSessionCursor reads ?after= into pg. It can't be combined with
+// ?offset=, which is kept for clients that page by number.
+func parseSessionCursor(q url.Values, pg *page) error {
+	v := q.Get("after")
+	if v == "" {
+		return nil
+	}
+	if q.Has("offset") {
+		return errors.New("use either after or offset, not both")
+	}
+	errBad := errors.New("after is not a valid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return errBad
+	}
+	start, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return errBad
+	}
+	var c sessionCursor
+	if c.Start, err = time.Parse(time.RFC3339Nano, start); err != nil {
+		return errBad
+	}
+	if c.ID, err = strToInt64(id); err != nil || c.ID <= 0 {
+		return errBad
+	}
+	pg.After = &c
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// An open session paused over lunch is matched on the time actually worked.
+func TestIncludeOpenExcludesPauses(t *testing.T) {
+	e := newTestEnv(t)
+	_, token := e.user("alice@example.com")
+
+	wantStatus(t, e.do("POST", "/api/time/start", token, nil), http.StatusCreated)
+	e.clock.advance(20 * time.Minute)
+	wantStatus(t, e.do("POST", "/api/time/pause", token, nil), http.StatusOK)
+	e.clock.advance(time.Hour)
+	wantStatus(t, e.do("POST", "/api/time/resume", token, nil), http.StatusOK)
+	e.clock.advance(10 * time.Minute) // 90 minutes wall clock, 30 worked
+
+	for _, c := range []struct {
+		query string
+		want  int
+	}{
+		{"minMinutes=30&includeOpen=true", 1},
+		{"minMinutes=31&includeOpen=true", 0},
+		{"maxMinutes=30&includeOpen=true", 1},
+		{"minMinutes=30", 0}, // open sessions only match with includeOpen
+	} {
+		rec := e.do("GET", "/api/time/sessions?"+c.query, token, nil)
+		wantStatus(t, rec, http.StatusOK)
+		var page SessionPage
+		decode(t, rec, &page)
+		if len(page.Items) != c.want {
+			t.Errorf("?%s matched %d sessions, want %d", c.query, len(page.Items), c.want)
+		}
+	}
+}
+
+func TestParseDateRangeInclusive(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	for _, c := range []struct {
+		from, to         string
+		wantFrom, wantTo time.Time
+	}{
+		// Plain dates are whole days in the user's zone.
+		{"2024-05-01", "2024-05-31",
+			time.Date(2024, 5, 1, 0, 0, 0, 0, berlin), time.Date(2024, 6, 1, 0, 0, 0, 0, berlin)},
+		{"2024-05-31", "2024-05-31",
+			time.Date(2024, 5, 31, 0, 0, 0, 0, berlin), time.Date(2024, 6, 1, 0, 0, 0, 0, berlin)},
+		// An RFC3339 to includes its own instant.
+		{"2024-05-31T09:00:00Z", "2024-05-31T09:00:00Z",
+			time.Date(2024, 5, 31, 9, 0, 0, 0, time.UTC), time.Date(2024, 5, 31, 9, 0, 0, 1000, time.UTC)},
+		{"2024-05-31", "2024-05-31T17:30:00+02:00",
+			time.Date(2024, 5, 31, 0, 0, 0, 0, berlin), time.Date(2024, 5, 31, 15, 30, 0, 1000, time.UTC)},
+	} {
+		rng, err := parseDateRange(url.Values{"from": {c.from}, "to": {c.to}}, berlin)
+		if err != nil {
+			t.Errorf("from=%s to=%s: %v", c.from, c.to, err)
+			continue
+		}
+		if !rng.From.Equal(c.wantFrom) || !rng.To.Equal(c.wantTo) {
+			t.Errorf("from=%s to=%s: [%v, %v), want [%v, %v)", c.from, c.to, rng.From, rng.To, c.wantFrom, c.wantTo)
+		}
+	}
+
+	if _, err := parseDateRange(url.Values{"from": {"2024-05-31T09:00:01Z"}, "to": {"2024-05-31T09:00:00Z"}}, berlin); err == nil {
+		t.Error("from after to was accepted")
+	}
+}
+
+// Listing with from == to == a session's exact start finds it.
+func TestListSessionsExactInstant(t *testing.T) {
+	e := newTestEnv(t)
+	_, token := e.user("alice@example.com")
+
+	start := testStart.Add(-2 * time.Hour)
+	wantStatus(t, e.do("POST", "/api/time/manual", token, map[string]any{
+		"startTime": start, "endTime": start.Add(time.Hour),
+	}), http.StatusCreated)
+
+	at := url.QueryEscape(start.Format(time.RFC3339))
+	before := url.QueryEscape(start.Add(-time.Second).Format(time.RFC3339))
+	for query, want := range map[string]int{
+		"from=" + at + "&to=" + at:               1,
+		"from=2024-03-04&to=2024-03-04":          1,
+		"from=2024-03-04T00:00:00Z&to=" + before: 0,
+	} {
+		rec := e.do("GET", "/api/time/sessions?"+query, token, nil)
+		wantStatus(t, rec, http.StatusOK)
+		var page SessionPage
+		decode(t, rec, &page)
+		if len(page.Items) != want {
+			t.Errorf("?%s matched %d sessions, want %d", query, len(page.Items), want)
+		}
+	}
+}
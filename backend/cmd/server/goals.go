@@ -0,0 +1,131 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+)
+
+//
+// ─────────────────────────────────── Goals ──────────────────────────────────
+//
+
+// Goals are the user's daily and weekly targets in minutes; nil = no goal.
+type Goals struct {
+	DailyMinutes  *int64 `json:"dailyMinutes"`
+	WeeklyMinutes *int64 `json:"weeklyMinutes"`
+}
+
+// maxGoalMinutes caps a target at the length of its period.
+var maxGoalMinutes = map[string]int64{"day": 24 * 60, "week": 7 * 24 * 60}
+
+// GoalProgress is merged into total-today / total-week when a goal is set.
+type GoalProgress struct {
+	TargetMinutes    int64 `json:"targetMinutes"`
+	RemainingMinutes int64 `json:"remainingMinutes"` // never below 0
+	GoalMet          bool  `json:"goalMet"`
+}
+
+// GET /api/goals
+// Returns the current targets.
+func (s *Server) getGoals(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+	s.writeGoals(w, uid)
+}
+
+// PUT /api/goals
+// Replaces both targets; a null or omitted field removes that goal.
+func (s *Server) putGoals(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	var req Goals
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+	targets := map[string]*int64{"day": req.DailyMinutes, "week": req.WeeklyMinutes}
+	for period, t := range targets {
+		if t != nil && (*t <= 0 || *t > maxGoalMinutes[period]) {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "goal minutes must be between 1 and the length of the period")
+			return
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	for period, t := range targets {
+		var err error
+		if t == nil {
+			_, err = tx.Exec(`DELETE FROM goals WHERE user_id=$1 AND period=$2`, uid, period)
+		} else {
+			_, err = tx.Exec(`
+				INSERT INTO goals(user_id, period, target_minutes) VALUES ($1,$2,$3)
+				ON CONFLICT (user_id, period) DO UPDATE SET target_minutes=EXCLUDED.target_minutes
+			`, uid, period, *t)
+		}
+		if err != nil {
+			serverError(w, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+	s.writeGoals(w, uid)
+}
+
+func (s *Server) writeGoals(w http.ResponseWriter, uid int64) {
+	rows, err := s.db.Query(`SELECT period, target_minutes FROM goals WHERE user_id=$1`, uid)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	var g Goals
+	for rows.Next() {
+		var period string
+		var t int64
+		if err := rows.Scan(&period, &t); err != nil {
+			serverError(w, err)
+			return
+		}
+		switch period {
+		case "day":
+			g.DailyMinutes = &t
+		case "week":
+			g.WeeklyMinutes = &t
+		}
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, g)
+}
+
+// goalProgress compares totalMinutes against the user's goal for period
+// ("day" or "week"); nil when no goal is set.
+func (s *Server) goalProgress(uid int64, period string, totalMinutes int64) (*GoalProgress, error) {
+	var target int64
+	err := s.db.QueryRow(
+		`SELECT target_minutes FROM goals WHERE user_id=$1 AND period=$2`, uid, period,
+	).Scan(&target)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &GoalProgress{
+		TargetMinutes:    target,
+		RemainingMinutes: max(0, target-totalMinutes),
+		GoalMet:          totalMinutes >= target,
+	}, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+//
+// ───────────────────────────────── Health ───────────────────────────────────
+//
+
+const readyTimeout = 2 * time.Second
+
+// GET /readyz
+// Readiness: 200 only if Postgres answers a ping within readyTimeout,
+// otherwise 503 with the failing check. /healthz stays a pure liveness probe
+// so a DB outage doesn't get every pod restarted.
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	if err := s.db.PingContext(ctx); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"status": "unavailable",
+			"checks": map[string]string{"database": err.Error()},
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "ready",
+		"checks": map[string]string{"database": "ok"},
+	})
+}
+
+// DebugHealth is the admin-only diagnostic snapshot of GET /debug/health.
+type DebugHealth struct {
+	Status        string        `json:"status"` // "ok" or "degraded" (DB ping failed)
+	UptimeSeconds int64         `json:"uptimeSeconds"`
+	Database      DatabaseStats `json:"database"`
+	OpenSessions  *int64        `json:"openSessions"` // across all users; null if the DB is down
+}
+
+type DatabaseStats struct {
+	PingMs          float64 `json:"pingMs"`
+	Error           string  `json:"error,omitempty"`
+	OpenConnections int     `json:"openConnections"`
+	InUse           int     `json:"inUse"`
+	Idle            int     `json:"idle"`
+	MaxOpen         int     `json:"maxOpen"`
+	WaitCount       int64   `json:"waitCount"`
+	WaitMs          int64   `json:"waitMs"`
+}
+
+// GET /debug/health
+// For incidents: DB ping latency, pool counters from db.Stats(), the number
+// of running sessions and the uptime in one response. Always 200 so the
+// numbers are there even when the DB is not; check "status".
+func (s *Server) debugHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	out := DebugHealth{Status: "ok", UptimeSeconds: int64(time.Since(s.startedAt) / time.Second)}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	start := time.Now()
+	err := s.db.PingContext(ctx)
+	out.Database.PingMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		out.Status, out.Database.Error = "degraded", err.Error()
+	} else {
+		var n int64
+		if err := s.db.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM sessions WHERE end_time IS NULL AND deleted_at IS NULL`,
+		).Scan(&n); err != nil {
+			out.Status, out.Database.Error = "degraded", err.Error()
+		} else {
+			out.OpenSessions = &n
+		}
+	}
+
+	st := s.db.Stats()
+	out.Database.OpenConnections = st.OpenConnections
+	out.Database.InUse = st.InUse
+	out.Database.Idle = st.Idle
+	out.Database.MaxOpen = st.MaxOpenConnections
+	out.Database.WaitCount = st.WaitCount
+	out.Database.WaitMs = st.WaitDuration.Milliseconds()
+
+	writeJSON(w, http.StatusOK, out)
+}
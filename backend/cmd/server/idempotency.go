@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+//
+// ────────────────────────────── Idempotency keys ────────────────────────────
+//
+
+// idempotent makes a POST safe to retry. A request carrying an
+// Idempotency-Key header runs once per user and key; repeats within
+// IDEMPOTENCY_TTL get the stored status and body back, marked with
+// Idempotent-Replayed: true. Repeating a key with a different endpoint or
+// body is 422; repeating it while the first request still runs is 409.
+// 5xx responses, handlers that panic and handlers that write nothing are
+// not stored, so a retry after any of those runs again.
+// Requests without the header are passed through untouched.
+// Must sit inside authOnly (keys are per user).
+func (s *Server) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if !validRequestID(key) { // same rules as X-Request-ID
+			writeError(w, http.StatusBadRequest, codeInvalidRequest,
+				"Idempotency-Key must be 1-128 printable ASCII characters")
+			return
+		}
+		uid, _ := userIDFromCtx(r)
+
+		body, err := io.ReadAll(r.Body) // bounded by limitBody
+		if err != nil {
+			writeErr(w, bodyError(err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), body...))
+		hash := hex.EncodeToString(sum[:])
+
+		now := s.clock.now()
+		claimed, err := s.claimIdempotencyKey(r.Context(), uid, key, hash, now)
+		if err != nil {
+			serverError(w, err)
+			return
+		}
+		if !claimed {
+			s.replayIdempotent(w, r, uid, key, hash)
+			return
+		}
+
+		rec := &recordingWriter{ResponseWriter: w}
+		completed := false
+		// Deferred so a panic (recoverer sits outside) still releases the
+		// key instead of leaving it in progress for the whole TTL.
+		defer func() { s.settleIdempotencyKey(uid, key, rec, completed) }()
+		next(rec, r)
+		completed = true
+	}
+}
+
+// settleIdempotencyKey stores the response recorded for a claimed key, or
+// releases the key when there is nothing worth replaying: a 5xx, a handler
+// that never returned, or one that wrote no status (replaying 0 would make
+// WriteHeader panic).
+func (s *Server) settleIdempotencyKey(uid int64, key string, rec *recordingWriter, completed bool) {
+	// Fresh context: the response is already out, so a client that
+	// hung up must not keep its retry from finding it.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var err error
+	if !completed || rec.status == 0 || rec.status >= 500 {
+		_, err = s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE user_id=$1 AND key=$2`, uid, key)
+	} else {
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE idempotency_keys SET status=$3, body=$4, location=NULLIF($5, '')
+			WHERE user_id=$1 AND key=$2
+		`, uid, key, rec.status, rec.body.Bytes(), rec.Header().Get("Location"))
+	}
+	if err != nil {
+		log.Printf("idempotency key %q of user %d: %v", key, uid, err)
+	}
+}
+
+// claimIdempotencyKey records the key as in flight. It returns false when
+// the key is already taken within the TTL; an expired row is taken over.
+func (s *Server) claimIdempotencyKey(ctx context.Context, uid int64, key, hash string, now time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys(user_id, key, request_hash, created_at) VALUES ($1,$2,$3,$4)
+		ON CONFLICT (user_id, key) DO UPDATE
+		SET request_hash=EXCLUDED.request_hash, status=NULL, body=NULL, location=NULL, created_at=EXCLUDED.created_at
+		WHERE idempotency_keys.created_at < $5
+	`, uid, key, hash, now, now.Add(-s.idempotencyTTL))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// replayIdempotent answers a repeated key from the stored response.
+func (s *Server) replayIdempotent(w http.ResponseWriter, r *http.Request, uid int64, key, hash string) {
+	var storedHash string
+	var status sql.NullInt64
+	var body []byte
+	var location sql.NullString
+	err := s.db.QueryRowContext(r.Context(), `
+		SELECT request_hash, status, body, location FROM idempotency_keys WHERE user_id=$1 AND key=$2
+	`, uid, key).Scan(&storedHash, &status, &body, &location)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The first request was released (5xx, panic, no response) between our claim and now.
+		writeError(w, http.StatusConflict, codeIdempotencyInProgress, "request with this Idempotency-Key is in progress; retry")
+		return
+	}
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	switch {
+	case storedHash != hash:
+		writeError(w, http.StatusUnprocessableEntity, codeIdempotencyKeyReused,
+			"Idempotency-Key was already used for a different request")
+	case !status.Valid:
+		writeError(w, http.StatusConflict, codeIdempotencyInProgress, "request with this Idempotency-Key is in progress; retry")
+	default:
+		h := w.Header()
+		if location.Valid {
+			h.Set("Location", location.String)
+		}
+		h.Set("Idempotent-Replayed", "true")
+		if len(body) > 0 {
+			h.Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(int(status.Int64))
+		w.Write(body)
+	}
+}
+
+// purgeIdempotencyKeys deletes keys created before cutoff; they could no
+// longer be replayed anyway.
+func (s *Server) purgeIdempotencyKeys(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE created_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// recordingWriter passes a response through while keeping a copy of its
+// status and body.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the real writer.
+func (rw *recordingWriter) Unwrap() http.ResponseWriter { return rw.ResponseWriter }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// A claimed key is released when the handler panics or writes nothing, so
+// the retry runs instead of hitting 409 idempotency_in_progress.
+func TestIdempotencyKeyReleased(t *testing.T) {
+	e := newTestEnv(t)
+	uid, _ := e.user("alice@example.com")
+
+	call := func(key string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/time/start", nil)
+		req.Header.Set("Idempotency-Key", key)
+		req = req.WithContext(context.WithValue(req.Context(), ctxUserID, uid))
+		rec := httptest.NewRecorder()
+		recoverer(e.s.idempotent(h)).ServeHTTP(rec, req)
+		return rec
+	}
+	created := func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusCreated, map[string]string{"ok": "yes"})
+	}
+
+	for name, first := range map[string]http.HandlerFunc{
+		"panic":   func(http.ResponseWriter, *http.Request) { panic("boom") },
+		"nothing": func(http.ResponseWriter, *http.Request) {},
+	} {
+		call("key-"+name, first)
+		rec := call("key-"+name, created)
+		if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+			t.Errorf("%s: retry = %d (replayed %q), want a fresh 201",
+				name, rec.Code, rec.Header().Get("Idempotent-Replayed"))
+		}
+	}
+
+	// A completed response is still replayed.
+	call("key-ok", created)
+	rec := call("key-ok", func(w http.ResponseWriter, r *http.Request) { t.Error("handler ran twice") })
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replay = %d (replayed %q), want stored 201", rec.Code, rec.Header().Get("Idempotent-Replayed"))
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//
+// ──────────────────────────────── Bulk import ───────────────────────────────
+//
+
+// maxImportRows bounds one import; larger histories go in several requests.
+const maxImportRows = 5000
+
+// importRow is one entry of POST /api/time/import. In CSV the header names
+// the columns (startTime, endTime, projectName, note; any order).
+type importRow struct {
+	StartTime   time.Time `json:"startTime"`
+	EndTime     time.Time `json:"endTime"`
+	ProjectName string    `json:"projectName"`
+	Note        string    `json:"note"`
+}
+
+// ImportError explains why one row (1-based, header not counted) was skipped.
+type ImportError struct {
+	Row    int    `json:"row"`
+	Reason string `json:"reason"`
+}
+
+// ImportResult is the summary returned by POST /api/time/import.
+type ImportResult struct {
+	Imported int           `json:"imported"`
+	Skipped  int           `json:"skipped"`
+	Errors   []ImportError `json:"errors"` // never null
+}
+
+// POST /api/time/import[?atomic=true]
+// Imports finished sessions from a JSON array or, with Content-Type text/csv,
+// a CSV file. Rows are validated like /api/time/manual (end after start, no
+// overlap with existing or earlier imported sessions); unknown project names
+// are created. Invalid rows are skipped and listed in errors; with
+// atomic=true any invalid row rejects the whole import (422) instead.
+func (s *Server) importSessions(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+	atomic := r.URL.Query().Get("atomic") == "true"
+
+	rows, err := readImportRows(r)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if len(rows) > maxImportRows {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest,
+			fmt.Sprintf("too many rows (max %d per import)", maxImportRows))
+		return
+	}
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	res := ImportResult{Errors: []ImportError{}}
+	projects := map[string]int64{} // lower-cased name → id
+	for i, row := range rows {
+		reason, err := s.importOne(tx, uid, row, projects)
+		if err != nil {
+			serverError(w, err)
+			return
+		}
+		if reason != "" {
+			res.Errors = append(res.Errors, ImportError{Row: i + 1, Reason: reason})
+			res.Skipped++
+			continue
+		}
+		res.Imported++
+	}
+
+	if atomic && len(res.Errors) > 0 {
+		writeErrorDetails(w, http.StatusUnprocessableEntity, codeImportRejected,
+			fmt.Sprintf("nothing imported: %d invalid row(s)", len(res.Errors)), res.Errors)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// importOne validates and inserts row. A non-empty reason means the row was
+// rejected; err is reserved for database failures.
+func (s *Server) importOne(tx *sql.Tx, uid int64, row importRow, projects map[string]int64) (reason string, err error) {
+	switch {
+	case row.StartTime.IsZero() || row.EndTime.IsZero():
+		return "startTime and endTime are required", nil
+	case !row.EndTime.After(row.StartTime):
+		return "endTime must be after startTime", nil
+	case checkNotFuture("endTime", row.EndTime, s.clock.now()) != nil:
+		return "endTime is in the future", nil
+	case len(row.Note) > maxNoteLen:
+		return errNoteTooLong.Error(), nil
+	case len(row.ProjectName) > maxProjectName:
+		return "projectName is too long", nil
+	}
+
+	ov, err := findOverlap(tx, uid, row.StartTime, &row.EndTime)
+	if err != nil {
+		return "", err
+	}
+	if ov != nil {
+		return "overlaps session " + int64ToStr(ov.ID), nil
+	}
+
+	var projectID *int64
+	if name := strings.TrimSpace(row.ProjectName); name != "" {
+		id, err := importProject(tx, uid, name, projects)
+		if err != nil {
+			return "", err
+		}
+		projectID = &id
+	}
+
+	roundTo, err := s.projectRoundTo(tx, projectID)
+	if err != nil {
+		return "", err
+	}
+	secs, dur, rounded := measure(row.StartTime, row.EndTime, 0, roundTo)
+	_, err = tx.Exec(`
+		INSERT INTO sessions(user_id, project_id, start_time, end_time, duration_seconds, duration_minutes,
+		                     rounded_minutes, note, tags)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+	`, uid, projectID, row.StartTime, row.EndTime, secs, dur, rounded, row.Note, pq.Array([]string{}))
+	return "", err
+}
+
+// importProject returns the id of uid's project called name (ignoring
+// case), creating it on first use.
+func importProject(tx *sql.Tx, uid int64, name string, cache map[string]int64) (int64, error) {
+	key := strings.ToLower(name)
+	if id, ok := cache[key]; ok {
+		return id, nil
+	}
+	var id int64
+	err := tx.QueryRow(`
+		SELECT id FROM projects WHERE user_id=$1 AND LOWER(name)=$2 ORDER BY id LIMIT 1
+	`, uid, key).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = tx.QueryRow(
+			`INSERT INTO projects(user_id, name) VALUES ($1,$2) RETURNING id`, uid, name,
+		).Scan(&id)
+	}
+	if err != nil {
+		return 0, err
+	}
+	cache[key] = id
+	return id, nil
+}
+
+// readImportRows decodes the request body as CSV or a JSON array.
+func readImportRows(r *http.Request) ([]importRow, error) {
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "text/csv" {
+		return readImportCSV(r.Body)
+	}
+	var rows []importRow
+	if err := decodeJSON(r, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// readImportCSV parses a CSV with a header row. Times are RFC3339; a bad
+// timestamp fails the whole request since it usually means a wrong format.
+func readImportCSV(body io.Reader) ([]importRow, error) {
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, csvError(err)
+	}
+	col := map[string]int{}
+	for i, name := range header {
+		col[strings.TrimSpace(name)] = i
+	}
+	if _, ok := col["startTime"]; !ok {
+		return nil, badRequest(codeInvalidRequest, "CSV header must include startTime and endTime")
+	}
+	if _, ok := col["endTime"]; !ok {
+		return nil, badRequest(codeInvalidRequest, "CSV header must include startTime and endTime")
+	}
+	field := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	var rows []importRow
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		if len(rows) == maxImportRows {
+			return nil, badRequest(codeInvalidRequest, fmt.Sprintf("too many rows (max %d per import)", maxImportRows))
+		}
+		row := importRow{ProjectName: field(rec, "projectName"), Note: field(rec, "note")}
+		if row.StartTime, err = time.Parse(time.RFC3339, field(rec, "startTime")); err != nil {
+			return nil, badRequest(codeInvalidRequest, fmt.Sprintf("row %d: startTime must be RFC3339", line))
+		}
+		if row.EndTime, err = time.Parse(time.RFC3339, field(rec, "endTime")); err != nil {
+			return nil, badRequest(codeInvalidRequest, fmt.Sprintf("row %d: endTime must be RFC3339", line))
+		}
+		rows = append(rows, row)
+	}
+}
+
+// csvError reports a malformed CSV (or an over-size body) as a client error.
+func csvError(err error) error {
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		return bodyError(err)
+	}
+	return badRequest(codeInvalidRequest, "bad csv: "+err.Error())
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+//
+// ─────────────────────────────── Background jobs ────────────────────────────
+//
+
+// runIntegrityCheck runs checkOpenSessions every interval until ctx is done.
+func (s *Server) runIntegrityCheck(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.checkOpenSessions(ctx)
+		}
+	}
+}
+
+// checkOpenSessions verifies the open-session limit (see beginSession).
+// New starts can't break it; this stays as a tripwire for writes that skip
+// beginSession, and also flags users left over the limit after it was
+// lowered.
+func (s *Server) checkOpenSessions(ctx context.Context) {
+	group := "user_id"
+	if s.openPerProject {
+		group = "user_id, project_id"
+	}
+	var users int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT user_id) FROM (
+			SELECT user_id
+			FROM sessions
+			WHERE end_time IS NULL AND deleted_at IS NULL
+			GROUP BY `+group+`
+			HAVING COUNT(*) > $1
+		) v
+	`, s.maxOpen).Scan(&users)
+	if err != nil {
+		log.Printf("integrity check failed: %v", err)
+		return
+	}
+	if users == 0 {
+		return
+	}
+
+	log.Printf("WARNING: %d user(s) are over the open-session limit of %d", users, s.maxOpen)
+	s.alert(ctx, "open_session_violation", map[string]any{"users": users})
+}
+
+// runTokenPurge calls purgeTokens every interval until ctx is done. Expired
+// idempotency keys go out in the same round.
+func (s *Server) runTokenPurge(ctx context.Context, every, retention time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if n, err := s.purgeTokens(ctx, s.clock.now().Add(-retention)); err != nil {
+				log.Printf("token purge failed: %v", err)
+			} else if n > 0 {
+				log.Printf("purged %d expired/revoked token(s)", n)
+			}
+			if n, err := s.purgeIdempotencyKeys(ctx, s.clock.now().Add(-s.idempotencyTTL)); err != nil {
+				log.Printf("idempotency key purge failed: %v", err)
+			} else if n > 0 {
+				log.Printf("purged %d expired idempotency key(s)", n)
+			}
+		}
+	}
+}
+
+// purgeTokens deletes access and refresh token rows that expired or were
+// revoked before cutoff. A missing row is as invalid as a revoked one to
+// authOnly and refresh, so this never resurrects or breaks a token; the
+// retention only keeps recent rows around for debugging.
+func (s *Server) purgeTokens(ctx context.Context, cutoff time.Time) (int64, error) {
+	var total int64
+	for _, table := range []string{"auth_tokens", "refresh_tokens"} {
+		res, err := s.db.ExecContext(ctx,
+			`DELETE FROM `+table+` WHERE expires_at < $1 OR revoked_at < $1`, cutoff)
+		if err != nil {
+			return total, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
+
+// runAutoStop calls autoStopSessions every interval until ctx is done.
+func (s *Server) runAutoStop(ctx context.Context, every, maxAge time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if n, err := s.autoStopSessions(ctx, s.clock.now(), maxAge); err != nil {
+				log.Printf("auto-stop failed: %v", err)
+			} else if n > 0 {
+				log.Printf("auto-stopped %d session(s) open longer than %s", n, maxAge)
+			}
+		}
+	}
+}
+
+// runArchive archives finished sessions older than maxAge every interval
+// until ctx is done (see archiveSessions).
+func (s *Server) runArchive(ctx context.Context, every, maxAge time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			now := s.clock.now()
+			if n, err := s.archiveSessions(ctx, 0, now.Add(-maxAge), now); err != nil {
+				log.Printf("archive failed: %v", err)
+			} else if n > 0 {
+				log.Printf("archived %d session(s) older than %s", n, maxAge)
+			}
+		}
+	}
+}
+
+// autoStopSessions closes sessions that have been open longer than maxAge,
+// as of now. They end at start + maxAge (not now: the extra hours were
+// never worked) and are flagged auto_stopped so the app can point them out.
+// Each owner's stream gets a "stopped" event once the sweep commits.
+// Rows locked by a concurrent stop are skipped and picked up next round.
+func (s *Server) autoStopSessions(ctx context.Context, now time.Time, maxAge time.Duration) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, start_time, project_id
+		FROM sessions
+		WHERE end_time IS NULL AND deleted_at IS NULL AND start_time < $1
+		FOR UPDATE SKIP LOCKED
+	`, now.Add(-maxAge))
+	if err != nil {
+		return 0, err
+	}
+	type stale struct {
+		id      int64
+		start   time.Time
+		project *int64
+	}
+	var todo []stale
+	for rows.Next() {
+		var st stale
+		if err := rows.Scan(&st.id, &st.start, &st.project); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		todo = append(todo, st)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	stopped := make([]Session, 0, len(todo))
+	for _, st := range todo {
+		end := st.start.Add(maxAge)
+		paused, _, err := pauseState(tx, st.id, end)
+		if err != nil {
+			return 0, err
+		}
+		if err := endPauses(tx, st.id, end); err != nil {
+			return 0, err
+		}
+		roundTo, err := s.projectRoundTo(tx, st.project)
+		if err != nil {
+			return 0, err
+		}
+		secs, dur, rounded := measure(st.start, end, paused, roundTo)
+		ss, err := scanSession(tx.QueryRowContext(ctx, `
+			UPDATE sessions
+			SET end_time=$2, duration_seconds=$3, duration_minutes=$4, rounded_minutes=$5, auto_stopped=true
+			WHERE id=$1
+			RETURNING `+sessionCols,
+			st.id, end, secs, dur, rounded))
+		if err != nil {
+			return 0, err
+		}
+		stopped = append(stopped, ss)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	for _, ss := range stopped {
+		s.streams.publish(ss.UserID, streamEvent{"stopped", ss})
+	}
+	return len(stopped), nil
+}
+
+//
+// ─────────────────────────────── Alerting hook ──────────────────────────────
+//
+
+// alert POSTs {event, at, details} to ALERT_WEBHOOK_URL (if configured).
+// Failures are logged only; alerting must never break the caller.
+func (s *Server) alert(ctx context.Context, event string, details map[string]any) {
+	s.metrics.alerts.WithLabelValues(event).Inc()
+	if s.alertURL == "" {
+		return
+	}
+	body, _ := json.Marshal(map[string]any{
+		"event":   event,
+		"at":      s.clock.now(),
+		"details": details,
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.alertURL, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("alert %s: %v", event, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("alert %s: %v", event, err)
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		log.Printf("alert %s: webhook returned %s", event, res.Status)
+	}
+}
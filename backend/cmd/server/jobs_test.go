@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAutoStopSweep(t *testing.T) {
+	e := newTestEnv(t)
+	aliceID, alice := e.user("alice@example.com")
+	_, bob := e.user("bob@example.com")
+	const maxAge = 12 * time.Hour
+
+	rec := e.do("POST", "/api/time/start", alice, nil)
+	wantStatus(t, rec, http.StatusCreated)
+	var stale Session
+	decode(t, rec, &stale)
+	e.clock.advance(time.Hour)
+	wantStatus(t, e.do("POST", "/api/time/pause", alice, nil), http.StatusOK)
+	e.clock.advance(30 * time.Minute)
+	wantStatus(t, e.do("POST", "/api/time/resume", alice, nil), http.StatusOK)
+
+	e.clock.advance(8*time.Hour + 30*time.Minute) // 10h in
+	rec = e.do("POST", "/api/time/start", bob, nil)
+	wantStatus(t, rec, http.StatusCreated)
+	var fresh Session
+	decode(t, rec, &fresh)
+
+	e.clock.advance(3 * time.Hour) // alice 13h in, bob 3h
+	events, unsubscribe := e.s.streams.subscribe(aliceID)
+	defer unsubscribe()
+	n, err := e.s.autoStopSessions(context.Background(), e.clock.now(), maxAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("auto-stopped %d sessions, want 1", n)
+	}
+
+	// A client watching the abandoned timer learns it ended.
+	select {
+	case ev := <-events:
+		if ev.Name != "stopped" {
+			t.Errorf("stream event %q, want stopped", ev.Name)
+		}
+	default:
+		t.Error("no stream event for the auto-stopped session")
+	}
+
+	rec = e.do("GET", fmt.Sprintf("/api/time/sessions/%d", stale.ID), alice, nil)
+	wantStatus(t, rec, http.StatusOK)
+	var got Session
+	decode(t, rec, &got)
+	// It ends at start + maxAge, not at the sweep, and the pause is excluded.
+	if got.EndTime == nil || !got.EndTime.Equal(testStart.Add(maxAge)) {
+		t.Errorf("endTime = %v, want start + 12h", got.EndTime)
+	}
+	if !got.AutoStopped {
+		t.Error("autoStopped = false")
+	}
+	if got.DurationMinutes == nil || *got.DurationMinutes != 11*60+30 {
+		t.Errorf("durationMinutes = %v, want 690", got.DurationMinutes)
+	}
+
+	rec = e.do("GET", fmt.Sprintf("/api/time/sessions/%d", fresh.ID), bob, nil)
+	wantStatus(t, rec, http.StatusOK)
+	decode(t, rec, &got)
+	if got.EndTime != nil || got.AutoStopped {
+		t.Errorf("younger session was touched: endTime=%v autoStopped=%v", got.EndTime, got.AutoStopped)
+	}
+
+	// Nothing left to do on the next round.
+	if n, err := e.s.autoStopSessions(context.Background(), e.clock.now(), maxAge); err != nil || n != 0 {
+		t.Errorf("second sweep = %d, %v; want 0, nil", n, err)
+	}
+}
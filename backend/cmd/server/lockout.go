@@ -0,0 +1,29 @@
+package main
+
+//
+// ─────────────────────────────── Account lockout ────────────────────────────
+//
+// Complements the in-memory rate limiter: the counter lives in the users
+// table, so it also catches attacks spread over many IPs or instances.
+// After lockoutThreshold consecutive bad passwords the account is locked
+// for lockoutDuration and the counter starts over.
+
+// recordFailedLogin bumps the counter and locks the account at the threshold.
+func (s *Server) recordFailedLogin(uid int64) error {
+	_, err := s.db.Exec(`
+		UPDATE users SET
+			locked_until = CASE WHEN failed_login_attempts + 1 >= $2 THEN $3 ELSE locked_until END,
+			failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= $2 THEN 0 ELSE failed_login_attempts + 1 END
+		WHERE id=$1
+	`, uid, s.lockoutThreshold, s.clock.now().Add(s.lockoutDuration))
+	return err
+}
+
+// resetFailedLogins clears the counter after a successful password check.
+func (s *Server) resetFailedLogins(uid int64) error {
+	_, err := s.db.Exec(`
+		UPDATE users SET failed_login_attempts=0, locked_until=NULL
+		WHERE id=$1 AND (failed_login_attempts <> 0 OR locked_until IS NOT NULL)
+	`, uid)
+	return err
+}
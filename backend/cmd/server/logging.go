@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//
+// ───────────────────────────────── Logging ──────────────────────────────────
+//
+
+// newLogger builds the process logger: JSON lines for log shippers or
+// human-readable text for a terminal (LOG_FORMAT).
+func newLogger(format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: slog.LevelInfo}
+	var h slog.Handler = slog.NewTextHandler(os.Stderr, opts)
+	if format == "json" {
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	return slog.New(requestIDHandler{h})
+}
+
+// requestIDHandler adds request_id to every record logged with a request
+// context (slog.InfoContext(r.Context(), ...) and friends).
+type requestIDHandler struct{ slog.Handler }
+
+func (h requestIDHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if id := requestIDFromCtx(ctx); id != "" {
+		rec.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{h.Handler.WithGroup(name)}
+}
+
+//
+// ──────────────────────────────── Request IDs ───────────────────────────────
+//
+
+const maxRequestIDLen = 128
+
+// requestID tags each request with an id for log correlation. An inbound
+// X-Request-ID (from a proxy or the app) is kept if it looks sane; otherwise
+// a fresh UUID is generated. The id is echoed back in the response header.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxRequestID, id)))
+	})
+}
+
+// validRequestID accepts short printable ASCII ids so a client can't inject
+// newlines or megabytes into our logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	return !strings.ContainsFunc(id, func(r rune) bool { return r < 0x21 || r > 0x7e })
+}
+
+// requestIDFromCtx returns the id set by requestID, or "".
+func requestIDFromCtx(ctx context.Context) string {
+	id, _ := ctx.Value(ctxRequestID).(string)
+	return id
+}
+
+// responseWriter remembers the status code and body size for logging.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach Flush & co. on the real writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter { return rw.ResponseWriter }
+
+//
+// ──────────────────────────────── Middleware ────────────────────────────────
+//
+
+// logging wraps the whole mux, so it sees every route including the ones
+// rejected by cors() or authOnly(). The request id is added by the handler.
+func logging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r)
+		if rw.status == 0 {
+			rw.status = http.StatusOK // handler wrote nothing
+		}
+		slog.LogAttrs(r.Context(), slog.LevelInfo, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("ip", clientIP(r)),
+			slog.Int("status", rw.status),
+			slog.Int("bytes", rw.bytes),
+			slog.Duration("duration", time.Since(start)),
+		)
+	})
+}
+
+// recoverer turns a panicking handler into a 500 instead of a dead process.
+// The stack goes to the log only; the client gets the generic error, and
+// only if no response has been started yet.
+func recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw, ok := w.(*responseWriter)
+		if !ok {
+			rw = &responseWriter{ResponseWriter: w}
+		}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(v) // deliberate abort; let net/http handle it
+			}
+			slog.ErrorContext(r.Context(), "panic in handler",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Any("panic", v),
+				slog.String("stack", string(debug.Stack())),
+			)
+			if rw.status == 0 {
+				writeError(rw, http.StatusInternalServerError, codeInternal, "internal server error")
+			}
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecovererCleanInternalError(t *testing.T) {
+	h := recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("nil map somewhere: secret detail")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/boom", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("Content-Type = %q, want JSON", ct)
+	}
+	var body struct {
+		Error errorBody `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q: %v", rec.Body.String(), err)
+	}
+	if body.Error.Code != codeInternal {
+		t.Errorf("code = %q, want %q", body.Error.Code, codeInternal)
+	}
+	if strings.Contains(rec.Body.String(), "secret detail") {
+		t.Error("panic value leaked to the client")
+	}
+}
+
+func TestRecovererAfterHeadersSent(t *testing.T) {
+	h := recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"partial":`))
+		panic("midway")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/boom", nil))
+
+	// Too late for a 500; the response is left as it was, not appended to.
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"partial":` {
+		t.Errorf("got %d %q, want the untouched partial response", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRecovererRepanicsAbort(t *testing.T) {
+	h := recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler passed through", v)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+// Behind the full test stack, a panicking route still gets a clean 500.
+func TestRecovererThroughMux(t *testing.T) {
+	s := newServer(testConfig(), nil)
+	mux := s.routes()
+	mux.HandleFunc("GET /test/panic", func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]int
+		m["x"]++
+	})
+	rec := httptest.NewRecorder()
+	recoverer(s.muxErrors(mux)).ServeHTTP(rec, httptest.NewRequest("GET", "/test/panic", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+}
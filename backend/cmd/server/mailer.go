@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+//
+// ─────────────────────────────────── Mail ───────────────────────────────────
+//
+
+// Mailer sends transactional mail (verification links, password resets).
+// Handlers only see this interface so tests can swap in a fake.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// logMailer writes messages to the server log instead of sending them.
+// Used when SMTP_ADDR is unset, which keeps local dev self-contained.
+type logMailer struct{}
+
+func (logMailer) Send(_ context.Context, to, subject, body string) error {
+	log.Printf("mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// smtpMailer delivers plain-text mail through an SMTP relay.
+type smtpMailer struct {
+	addr string    // host:port
+	from string    // envelope and header From
+	auth smtp.Auth // nil when the relay needs no credentials
+}
+
+func newSMTPMailer(addr, from, user, password string) *smtpMailer {
+	m := &smtpMailer{addr: addr, from: from}
+	if user != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		m.auth = smtp.PlainAuth("", user, password, host)
+	}
+	return m
+}
+
+func (m *smtpMailer) Send(_ context.Context, to, subject, body string) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("mail header contains a line break")
+	}
+	msg := "From: " + m.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" + body
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(msg))
+}
+
+// newMailer picks the SMTP mailer when configured, the log mailer otherwise.
+func newMailer(cfg Config) Mailer {
+	if cfg.SMTPAddr == "" {
+		return logMailer{}
+	}
+	return newSMTPMailer(cfg.SMTPAddr, cfg.MailFrom, cfg.SMTPUser, cfg.SMTPPassword)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestShutdownFinishesInFlight runs the same http.Server main builds and
+// checks Shutdown waits for a request that is still being handled.
+func TestShutdownFinishesInFlight(t *testing.T) {
+	cfg := testConfig()
+	cfg.ReadHeaderTimeout = 5 * time.Second
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		writeJSON(w, http.StatusOK, map[string]string{"status": "done"})
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := cfg.httpServer(ln.Addr().String(), h)
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	got := make(chan result, 1)
+	go func() {
+		res, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			got <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		got <- result{res.StatusCode, string(b), err}
+	}()
+	<-entered
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- srv.Shutdown(ctx)
+	}()
+
+	// Shutdown must still be waiting while the handler runs.
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown returned %v before the in-flight request finished", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+
+	r := <-got
+	if r.err != nil || r.status != http.StatusOK {
+		t.Fatalf("in-flight request: status %d, err %v", r.status, r.err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Serve returned %v, want ErrServerClosed", err)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+//
+// ───────────────────────────────── Metrics ──────────────────────────────────
+//
+
+// metrics holds the Prometheus collectors. They live in a private registry
+// so /metrics shows exactly what we register (plus Go/process stats).
+type metrics struct {
+	reg      *prometheus.Registry
+	requests *prometheus.CounterVec   // by route, method, status
+	latency  *prometheus.HistogramVec // by route, method
+	logins   *prometheus.CounterVec   // by result: success | failure
+	alerts   *prometheus.CounterVec   // by event
+}
+
+func newMetrics(db *sql.DB) *metrics {
+	m := &metrics{
+		reg: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "timetrac_http_requests_total",
+			Help: "HTTP requests by route pattern, method and status code.",
+		}, []string{"route", "method", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "timetrac_http_request_duration_seconds",
+			Help:    "HTTP request latency by route pattern and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "timetrac_logins_total",
+			Help: "Login attempts by result.",
+		}, []string{"result"}),
+		alerts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "timetrac_alerts_total",
+			Help: "Operational alerts raised by background checks.",
+		}, []string{"event"}),
+	}
+	openSessions := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "timetrac_open_sessions",
+		Help: "Sessions currently running (end_time IS NULL).",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		var n int
+		if err := db.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM sessions WHERE end_time IS NULL AND deleted_at IS NULL`,
+		).Scan(&n); err != nil {
+			log.Printf("metrics: count open sessions: %v", err)
+			return -1
+		}
+		return float64(n)
+	})
+	m.reg.MustRegister(
+		m.requests, m.latency, m.logins, m.alerts, openSessions,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// handler serves the registry in the Prometheus text format.
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.reg, promhttp.HandlerOpts{})
+}
+
+// instrument records count and latency for every request. The route label
+// is the mux pattern's path (e.g. /api/projects/{id}), never the raw path, so ids
+// don't explode the series count.
+func (m *metrics) instrument(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw, ok := w.(*responseWriter)
+		if !ok {
+			rw = &responseWriter{ResponseWriter: w}
+		}
+		next.ServeHTTP(rw, r)
+
+		route := "unmatched"
+		if _, pattern := mux.Handler(r); pattern != "" {
+			_, route, _ = strings.Cut(pattern, " ") // "GET /x" → "/x"; method is its own label
+		}
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		m.requests.WithLabelValues(route, r.Method, strconv.Itoa(status)).Inc()
+		m.latency.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+func (m *metrics) login(ok bool) {
+	if ok {
+		m.logins.WithLabelValues("success").Inc()
+	} else {
+		m.logins.WithLabelValues("failure").Inc()
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"strings"
+)
+
+//
+// ──────────────────────────────── Migrations ────────────────────────────────
+//
+
+// Schema changes live in migrations/NNNN_name.sql and are applied in name
+// order, each in its own transaction, and recorded in schema_migrations.
+// Never edit a shipped migration; add a new one.
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// migrationLock is an arbitrary pg_advisory_lock key so that replicas
+// starting together don't race each other through the same migration.
+const migrationLock = 727_001
+
+// migrate brings the schema up to date. Safe to run on every boot.
+func migrate(ctx context.Context, db *sql.DB) error {
+	// Advisory locks belong to a connection, so pin one for the whole run.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLock); err != nil {
+		return fmt.Errorf("lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLock)
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		  version TEXT PRIMARY KEY,
+		  applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`); err != nil {
+		return err
+	}
+
+	applied := map[string]bool{}
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[v] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	files, err := fs.ReadDir(migrationFS, "migrations") // sorted by name
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		version := strings.TrimSuffix(f.Name(), ".sql")
+		if applied[version] {
+			continue
+		}
+		src, err := migrationFS.ReadFile("migrations/" + f.Name())
+		if err != nil {
+			return err
+		}
+		if err := applyMigration(ctx, conn, version, string(src)); err != nil {
+			return fmt.Errorf("migration %s: %w", version, err)
+		}
+		log.Printf("migration %s applied", version)
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, conn *sql.Conn, version, src string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// No args: lib/pq sends it as a simple query, so multiple statements work.
+	if _, err := tx.ExecContext(ctx, src); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations(version) VALUES ($1)`, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"testing"
+)
+
+// legacyInitSQL is the schema part of the original db/init.sql, which
+// docker-compose ran on first boot before migrations existed.
+const legacyInitSQL = `
+CREATE TABLE IF NOT EXISTS users (
+  id SERIAL PRIMARY KEY,
+  email TEXT UNIQUE NOT NULL,
+  password_hash TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE TABLE IF NOT EXISTS auth_tokens (
+  jti UUID PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  expires_at TIMESTAMPTZ NOT NULL,
+  revoked_at TIMESTAMPTZ
+);
+CREATE TABLE IF NOT EXISTS sessions (
+  id BIGSERIAL PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  start_time TIMESTAMPTZ NOT NULL,
+  end_time   TIMESTAMPTZ,
+  duration_minutes INT
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_date ON sessions (user_id, start_time);
+INSERT INTO users(email, password_hash) VALUES ('demo@demo.io', 'x');
+INSERT INTO sessions(user_id, start_time, end_time, duration_minutes)
+  SELECT id, NOW() - INTERVAL '1 hour', NOW(), 60 FROM users;
+`
+
+func TestMigrateEmptyDatabase(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	if err := migrate(ctx, db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	files, err := fs.ReadDir(migrationFS, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := countMigrations(t, db); n != len(files) {
+		t.Errorf("schema_migrations has %d rows, want %d", n, len(files))
+	}
+	assertSchema(t, db)
+
+	// A second run finds nothing to do.
+	if err := migrate(ctx, db); err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	if n := countMigrations(t, db); n != len(files) {
+		t.Errorf("after re-run schema_migrations has %d rows, want %d", n, len(files))
+	}
+}
+
+func TestMigrateLegacyInitScript(t *testing.T) {
+	db := testDB(t)
+	if _, err := db.Exec(legacyInitSQL); err != nil {
+		t.Fatalf("legacy init: %v", err)
+	}
+	if err := migrate(context.Background(), db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	assertSchema(t, db)
+
+	// Existing rows survive and pick up the new columns' defaults.
+	var note string
+	var tags []byte
+	if err := db.QueryRow(`SELECT note, tags FROM sessions`).Scan(&note, &tags); err != nil {
+		t.Fatalf("legacy session: %v", err)
+	}
+	if note != "" || string(tags) != "{}" {
+		t.Errorf("legacy session note=%q tags=%s, want empty", note, tags)
+	}
+}
+
+func countMigrations(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// assertSchema checks a sample of columns that only later migrations add.
+func assertSchema(t *testing.T, db *sql.DB) {
+	t.Helper()
+	for _, c := range []struct{ table, column string }{
+		{"users", "email_verified"},
+		{"users", "locked_until"},
+		{"projects", "hourly_rate_cents"},
+		{"sessions", "project_id"},
+		{"sessions", "rounded_minutes"},
+		{"sessions", "deleted_at"},
+		{"sessions", "duration_seconds"},
+		{"sessions", "version"},
+		{"refresh_tokens", "jti"},
+		{"password_resets", "token_hash"},
+	} {
+		var ok bool
+		if err := db.QueryRow(`
+			SELECT EXISTS (
+			  SELECT 1 FROM information_schema.columns
+			  WHERE table_name=$1 AND column_name=$2
+			)`, c.table, c.column).Scan(&ok); err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Errorf("missing column %s.%s", c.table, c.column)
+		}
+	}
+}
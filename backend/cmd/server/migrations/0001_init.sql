@@ -1,3 +1,8 @@
+-- 0001: baseline schema, exactly what the old db/init.sql created at first
+-- (minus its demo user). Everything added to init.sql after that comes in
+-- 0001_init_upgrade, so a database bootstrapped from any version of the old
+-- script ends up with the same schema as a fresh one.
+
 CREATE TABLE IF NOT EXISTS users (
   id SERIAL PRIMARY KEY,
   email TEXT UNIQUE NOT NULL,
@@ -22,12 +27,3 @@ CREATE TABLE IF NOT EXISTS sessions (
 );
 
 CREATE INDEX IF NOT EXISTS idx_sessions_user_date ON sessions (user_id, start_time);
-
--- حساب تجريبي (email: demo@demo.io / pass: demo123)
-DO $$
-BEGIN
-  IF NOT EXISTS (SELECT 1 FROM users WHERE email='demo@demo.io') THEN
-    INSERT INTO users(email, password_hash)
-    VALUES ('demo@demo.io', '$2a$10$k1QfQ8m2QW1Jt9y0S1V9N.4j5c9Tnq2E0n6tM3e6F3tFZ0S0h8UFe'); -- bcrypt('demo123')
-  END IF;
-END $$;
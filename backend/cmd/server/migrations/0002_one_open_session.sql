@@ -0,0 +1,16 @@
+-- 0002: at most one running session per user, enforced by the database so
+-- concurrent /api/time/start requests can't both succeed.
+
+-- Close any duplicates left over from before (keep the newest one running)
+-- as zero-length sessions, so the index can be built.
+UPDATE sessions s
+SET end_time = s.start_time, duration_minutes = 0, rounded_minutes = 0
+WHERE s.end_time IS NULL AND s.deleted_at IS NULL
+  AND EXISTS (
+    SELECT 1 FROM sessions n
+    WHERE n.user_id = s.user_id AND n.end_time IS NULL AND n.deleted_at IS NULL
+      AND (n.start_time, n.id) > (s.start_time, s.id)
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_one_open
+  ON sessions (user_id) WHERE end_time IS NULL AND deleted_at IS NULL;
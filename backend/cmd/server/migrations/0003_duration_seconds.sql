@@ -0,0 +1,7 @@
+-- 0003: store exact durations. duration_minutes stays (derived, floor of
+-- seconds / 60) for older clients.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS duration_seconds INT;
+
+UPDATE sessions
+SET duration_seconds = GREATEST(0, FLOOR(EXTRACT(EPOCH FROM (end_time - start_time))))::int
+WHERE end_time IS NOT NULL AND duration_seconds IS NULL;
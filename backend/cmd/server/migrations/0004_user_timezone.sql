@@ -0,0 +1,2 @@
+-- 0004: per-user IANA timezone for day boundaries ('' = not set → UTC).
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+-- 0005: pause/resume inside a running session. Paused time is subtracted
+-- from the session's duration on stop.
+CREATE TABLE IF NOT EXISTS session_pauses (
+  id BIGSERIAL PRIMARY KEY,
+  session_id BIGINT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+  paused_at TIMESTAMPTZ NOT NULL,
+  resumed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_pauses_session ON session_pauses (session_id);
+
+-- A session can only be paused once at a time.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_session_pauses_open
+  ON session_pauses (session_id) WHERE resumed_at IS NULL;
@@ -0,0 +1,2 @@
+-- 0006: sessions closed by the idle auto-stop job instead of the user.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS auto_stopped BOOLEAN NOT NULL DEFAULT false;
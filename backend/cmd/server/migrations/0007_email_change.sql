@@ -0,0 +1,5 @@
+-- 0007: email changes wait in users.pending_email until the new address is
+-- confirmed; the verification row records which address its link confirms
+-- (NULL = the account's current email, as mailed on register).
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_email TEXT;
+ALTER TABLE email_verifications ADD COLUMN IF NOT EXISTS email TEXT;
@@ -0,0 +1,15 @@
+-- 0009: sessions.updated_at, bumped on every UPDATE (edits, stop, soft
+-- delete) by a trigger, so the list ETag is a cheap MAX() + COUNT().
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE OR REPLACE FUNCTION touch_updated_at() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at = NOW();
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS sessions_touch_updated_at ON sessions;
+CREATE TRIGGER sessions_touch_updated_at
+  BEFORE UPDATE ON sessions
+  FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
@@ -0,0 +1,8 @@
+-- 0010: per-user time targets. One row per (user, period); no row means no
+-- goal for that period.
+CREATE TABLE IF NOT EXISTS goals (
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  period TEXT NOT NULL CHECK (period IN ('day', 'week')),
+  target_minutes INT NOT NULL CHECK (target_minutes > 0),
+  PRIMARY KEY (user_id, period)
+);
@@ -0,0 +1,4 @@
+-- 0011: billing. A NULL rate marks the project as non-billable; amounts are
+-- integer cents of the project's ISO 4217 currency.
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS hourly_rate_cents BIGINT CHECK (hourly_rate_cents > 0);
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'EUR';
@@ -0,0 +1,12 @@
+-- 0012: optional TOTP two-factor auth. totp_secret is AES-GCM sealed with
+-- TOTP_ENCRYPTION_KEY (see totp.go) and only counts once totp_enabled is set.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT false;
+
+-- Single-use recovery codes, hashed like the mailed tokens.
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash TEXT NOT NULL,
+  used_at TIMESTAMPTZ,
+  PRIMARY KEY (user_id, code_hash)
+);
@@ -0,0 +1,16 @@
+-- 0014: optimistic locking for session edits. The trigger bumps version on
+-- every UPDATE (edits, stop, pause bookkeeping, soft delete), so a PATCH
+-- carrying an older version is known to be stale.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION bump_version() RETURNS trigger AS $$
+BEGIN
+  NEW.version = OLD.version + 1;
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS sessions_bump_version ON sessions;
+CREATE TRIGGER sessions_bump_version
+  BEFORE UPDATE ON sessions
+  FOR EACH ROW EXECUTE FUNCTION bump_version();
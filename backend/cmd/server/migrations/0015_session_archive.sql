@@ -0,0 +1,8 @@
+-- 0015: archived sessions. Old finished sessions get archived_at set (by
+-- POST /api/time/archive or the ARCHIVE_AFTER_DAYS job) and drop out of the
+-- default session list; totals and reports still count them.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ NULL;
+
+-- The default listSessions query only touches live rows.
+CREATE INDEX IF NOT EXISTS idx_sessions_user_live
+  ON sessions (user_id, start_time) WHERE archived_at IS NULL AND deleted_at IS NULL;
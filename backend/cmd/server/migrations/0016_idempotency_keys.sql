@@ -0,0 +1,17 @@
+-- 0016: Idempotency-Key support for POST /api/time/start and /stop. The
+-- first request with a key stores its response; retries within
+-- IDEMPOTENCY_TTL get that response replayed instead of running again.
+-- status stays NULL while the first request is still in flight.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  key TEXT NOT NULL,
+  request_hash TEXT NOT NULL,
+  status INT,
+  body BYTEA,
+  location TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, key)
+);
+
+-- The purge job deletes by age.
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys (created_at);
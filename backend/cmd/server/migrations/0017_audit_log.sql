@@ -0,0 +1,17 @@
+-- 0017: trail of security-relevant events (logins, logouts, password and
+-- 2FA changes, revocations, account deletion). user_id has no foreign key
+-- on purpose: the account_deleted entry must outlive the user row. It is
+-- NULL for failed logins with an unknown email.
+CREATE TABLE IF NOT EXISTS audit_log (
+  id BIGSERIAL PRIMARY KEY,
+  user_id INT,
+  event TEXT NOT NULL,
+  ip TEXT NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  meta JSONB NOT NULL DEFAULT '{}'
+);
+
+-- GET /auth/audit (own events, newest first) and the admin listing.
+CREATE INDEX IF NOT EXISTS idx_audit_log_user ON audit_log (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log (created_at DESC);
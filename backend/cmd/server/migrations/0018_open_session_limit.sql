@@ -0,0 +1,8 @@
+-- 0018: the number of open sessions per user is configurable now
+-- (MAX_OPEN_SESSIONS_PER_USER, OPEN_SESSION_SCOPE). beginSession enforces it
+-- under a per-user advisory lock, so the unique index from 0002 goes; a
+-- plain one keeps the open-session lookups fast.
+DROP INDEX IF EXISTS idx_sessions_one_open;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_open_user
+  ON sessions (user_id, project_id) WHERE end_time IS NULL AND deleted_at IS NULL;
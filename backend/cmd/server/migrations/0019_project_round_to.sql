@@ -0,0 +1,3 @@
+-- 0019: per-project rounding increment. NULL = use ROUND_TO_MINUTES; a
+-- {roundTo} sent on stop still wins over both (see rounding.go).
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS round_to INT CHECK (round_to BETWEEN 1 AND 60 AND 60 % round_to = 0);
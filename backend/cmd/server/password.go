@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"unicode"
+)
+
+//
+// ─────────────────────────────── Password policy ────────────────────────────
+//
+
+// PasswordPolicy is applied wherever a new password is chosen: register,
+// change-password and reset-password (via their Validate methods).
+// Configured via PASSWORD_* env vars.
+type PasswordPolicy struct {
+	MinLength     int  `json:"minLength"`
+	RequireDigit  bool `json:"requireDigit"`
+	RequireLetter bool `json:"requireLetter"`
+}
+
+// Rule codes reported when a password is rejected.
+const (
+	ruleMinLength = "min_length"
+	ruleMaxLength = "max_length"
+	ruleDigit     = "digit"
+	ruleLetter    = "letter"
+)
+
+// maxPasswordBytes is bcrypt's input limit. Longer passwords used to be
+// truncated silently (so any two sharing the first 72 bytes matched); now
+// they are rejected up front instead.
+const maxPasswordBytes = 72
+
+// PasswordRuleFailure names one unmet rule for the UI to explain.
+type PasswordRuleFailure struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Check returns every rule pw breaks; nil means it's acceptable.
+func (p PasswordPolicy) Check(pw string) []PasswordRuleFailure {
+	var failed []PasswordRuleFailure
+	if n := len([]rune(pw)); n < p.MinLength {
+		failed = append(failed, PasswordRuleFailure{ruleMinLength,
+			fmt.Sprintf("must be at least %d characters", p.MinLength)})
+	}
+	if len(pw) > maxPasswordBytes {
+		failed = append(failed, PasswordRuleFailure{ruleMaxLength,
+			fmt.Sprintf("must be at most %d bytes (fewer characters if they are not ASCII)", maxPasswordBytes)})
+	}
+	var digit, letter bool
+	for _, r := range pw {
+		digit = digit || unicode.IsDigit(r)
+		letter = letter || unicode.IsLetter(r)
+	}
+	if p.RequireDigit && !digit {
+		failed = append(failed, PasswordRuleFailure{ruleDigit, "must contain a digit"})
+	}
+	if p.RequireLetter && !letter {
+		failed = append(failed, PasswordRuleFailure{ruleLetter, "must contain a letter"})
+	}
+	return failed
+}
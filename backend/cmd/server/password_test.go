@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BCRYPT_COST applies to every newly set password; old hashes keep theirs.
+func TestBcryptCostApplied(t *testing.T) {
+	cfg := testConfig()
+	cfg.BcryptCost = bcrypt.MinCost + 1
+	e := newTestEnvConfig(t, cfg)
+
+	hashCost := func(email string) int {
+		t.Helper()
+		var hash string
+		if err := e.s.db.QueryRow(`SELECT password_hash FROM users WHERE email=$1`, email).Scan(&hash); err != nil {
+			t.Fatal(err)
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			t.Fatalf("stored hash: %v", err)
+		}
+		return cost
+	}
+
+	wantStatus(t, e.do("POST", "/auth/register", "", map[string]string{
+		"email": "carol@example.com", "password": "password123",
+	}), http.StatusCreated)
+	if c := hashCost("carol@example.com"); c != cfg.BcryptCost {
+		t.Errorf("register stored cost %d, want %d", c, cfg.BcryptCost)
+	}
+
+	// e.user seeds its hash at MinCost; changing the password rehashes it.
+	_, token := e.user("alice@example.com")
+	if c := hashCost("alice@example.com"); c != bcrypt.MinCost {
+		t.Fatalf("seeded cost %d, want %d", c, bcrypt.MinCost)
+	}
+	wantStatus(t, e.do("POST", "/auth/change-password", token, map[string]string{
+		"oldPassword": "password123", "newPassword": "password456",
+	}), http.StatusOK)
+	if c := hashCost("alice@example.com"); c != cfg.BcryptCost {
+		t.Errorf("change-password stored cost %d, want %d", c, cfg.BcryptCost)
+	}
+}
+
+// The limit is bcrypt's 72 bytes, not 72 characters.
+func TestPasswordPolicyMaxBytes(t *testing.T) {
+	p := PasswordPolicy{MinLength: 8}
+	hasMax := func(pw string) bool {
+		for _, f := range p.Check(pw) {
+			if f.Rule == ruleMaxLength {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, c := range []struct {
+		name string
+		pw   string
+		want bool
+	}{
+		{"72 ASCII bytes", strings.Repeat("a", 72), false},
+		{"73 ASCII bytes", strings.Repeat("a", 73), true},
+		{"24 euro signs, 72 bytes", strings.Repeat("€", 24), false},
+		{"25 euro signs, 75 bytes", strings.Repeat("€", 25), true},
+		{"71 bytes then a 3-byte rune", strings.Repeat("a", 71) + "€", true},
+	} {
+		if got := hasMax(c.pw); got != c.want {
+			t.Errorf("%s (%d runes, %d bytes): max_length reported = %v, want %v",
+				c.name, utf8.RuneCountInString(c.pw), len(c.pw), got, c.want)
+		}
+	}
+}
+
+// Passwords that only differ after byte 72 used to hash identically; the
+// longer one is now refused before it reaches bcrypt.
+func TestRegisterRejectsOverlongPassword(t *testing.T) {
+	e := newTestEnv(t)
+	rec := e.do("POST", "/auth/register", "", map[string]string{
+		"email": "dave@example.com", "password": strings.Repeat("a", 72) + "X",
+	})
+	wantStatus(t, rec, http.StatusUnprocessableEntity)
+	var body struct {
+		Error struct {
+			Code    string       `json:"code"`
+			Details []FieldError `json:"details"`
+		} `json:"error"`
+	}
+	decode(t, rec, &body)
+	if body.Error.Code != codeValidationFailed || len(body.Error.Details) != 1 ||
+		body.Error.Details[0].Field != "password" || body.Error.Details[0].Code != ruleMaxLength {
+		t.Errorf("error = %+v, want one password max_length failure", body.Error)
+	}
+
+	wantStatus(t, e.do("POST", "/auth/register", "", map[string]string{
+		"email": "dave@example.com", "password": strings.Repeat("a", 72),
+	}), http.StatusCreated)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+//
+// ────────────────────────────── Pause / resume ──────────────────────────────
+//
+// A running session can be paused (lunch) and resumed any number of times;
+// each pause is a session_pauses row. Only active time counts: stop and
+// the other places that close a session subtract the paused spans.
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// pausedSQL sums the paused seconds of sessions.id up to the timestamp
+// placeholder at, clipping pauses that are still open (or reach past at).
+func pausedSQL(at string) string {
+	return `(SELECT COALESCE(SUM(GREATEST(0, EXTRACT(EPOCH FROM
+		(LEAST(COALESCE(p.resumed_at, ` + at + `), ` + at + `) - p.paused_at)))), 0)
+		FROM session_pauses p WHERE p.session_id = sessions.id)`
+}
+
+// pauseState returns how long session id has been paused up to at, and
+// whether it is paused right now.
+func pauseState(q queryer, id int64, at time.Time) (paused time.Duration, pausedNow bool, err error) {
+	var secs float64
+	err = q.QueryRow(`
+		SELECT `+pausedSQL("$2")+`,
+		       EXISTS (SELECT 1 FROM session_pauses WHERE session_id=$1 AND resumed_at IS NULL)
+		FROM sessions WHERE id=$1
+	`, id, at).Scan(&secs, &pausedNow)
+	return time.Duration(secs * float64(time.Second)), pausedNow, err
+}
+
+// endPauses resumes an open pause of session id at the given time, used
+// when the session itself ends.
+func endPauses(q execer, id int64, at time.Time) error {
+	_, err := q.Exec(
+		`UPDATE session_pauses SET resumed_at=GREATEST(paused_at, $2) WHERE session_id=$1 AND resumed_at IS NULL`,
+		id, at,
+	)
+	return err
+}
+
+// Optional body for POST /api/time/pause and /resume. Without it the
+// oldest open session is picked (preferring one that can be paused or
+// resumed), which is all there is under the default one-session limit.
+type pauseReq struct {
+	SessionID *int64 `json:"sessionId"` // this open session
+	ProjectID *int64 `json:"projectId"` // the oldest open session in this project
+}
+
+// lockOpenSession picks uid's open session as pauseReq describes and locks
+// it for the rest of tx, like stopSession does, so a concurrent stop or
+// pause can't interleave. Among several candidates, paused ones come first
+// when resuming and last when pausing. sql.ErrNoRows if there is none.
+func lockOpenSession(tx *sql.Tx, uid int64, req pauseReq, resuming bool) (int64, error) {
+	order := "ASC"
+	if resuming {
+		order = "DESC"
+	}
+	var id int64
+	err := tx.QueryRow(`
+		SELECT id FROM sessions
+		WHERE user_id=$1 AND end_time IS NULL AND deleted_at IS NULL
+		  AND ($2::bigint IS NULL OR id=$2) AND ($3::bigint IS NULL OR project_id=$3)
+		ORDER BY EXISTS (
+		  SELECT 1 FROM session_pauses p WHERE p.session_id = sessions.id AND p.resumed_at IS NULL
+		) `+order+`, start_time ASC, id ASC
+		LIMIT 1
+		FOR UPDATE
+	`, uid, req.SessionID, req.ProjectID).Scan(&id)
+	return id, err
+}
+
+// POST /api/time/pause
+// Pauses an open session: body {sessionId} or {projectId} picks which when
+// several run, else the oldest one that isn't paused. 404 if no open
+// session matches, 409 if it is already paused.
+func (s *Server) pauseSession(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	var req pauseReq
+	if err := decodeOptionalJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeErr(w, err)
+		return
+	}
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	id, err := lockOpenSession(tx, uid, req, false)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, codeNoOpenSession, "no open session")
+		return
+	}
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+
+	now := s.clock.now()
+	_, err = tx.Exec(`INSERT INTO session_pauses(session_id, paused_at) VALUES ($1,$2)`, id, now)
+	switch {
+	case isUniqueViolation(err, "idx_session_pauses_open"):
+		writeError(w, http.StatusConflict, codeSessionPaused, "session is already paused")
+		return
+	case err != nil:
+		serverError(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"sessionId": id, "pausedAt": now})
+}
+
+// POST /api/time/resume
+// Resumes a paused open session, picked like POST /api/time/pause (without
+// a body: the oldest paused one), and returns {sessionId, resumedAt,
+// pausedSeconds} (total paused so far). 404 if no open session matches,
+// 409 if it isn't paused.
+func (s *Server) resumeSession(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	var req pauseReq
+	if err := decodeOptionalJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeErr(w, err)
+		return
+	}
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	id, err := lockOpenSession(tx, uid, req, true)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, codeNoOpenSession, "no open session")
+		return
+	}
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+
+	now := s.clock.now()
+	res, err := tx.Exec(
+		`UPDATE session_pauses SET resumed_at=$2 WHERE session_id=$1 AND resumed_at IS NULL`, id, now,
+	)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusConflict, codeSessionNotPaused, "session is not paused")
+		return
+	}
+	paused, _, err := pauseState(tx, id, now)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sessionId": id, "resumedAt": now, "pausedSeconds": int64(paused / time.Second),
+	})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// With several sessions running, sessionId/projectId pick which one to
+// pause or resume, and a bare resume finds the paused one.
+func TestPauseResumePicksSession(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxOpenSessions = 3
+	e := newTestEnvConfig(t, cfg)
+	_, token := e.user("alice@example.com")
+	_, bobToken := e.user("bob@example.com")
+	acme := e.project(token, map[string]any{"name": "Acme"}).ID
+
+	start := func(body any) int64 {
+		rec := e.do("POST", "/api/time/start", token, body)
+		wantStatus(t, rec, http.StatusCreated)
+		var ss Session
+		decode(t, rec, &ss)
+		e.clock.advance(time.Minute)
+		return ss.ID
+	}
+	oldest, newer := start(nil), start(nil)
+	inAcme := start(map[string]any{"projectId": acme})
+
+	// call returns the status and, on success, the session acted on.
+	call := func(path string) func(body any) (int, int64) {
+		return func(body any) (int, int64) {
+			rec := e.do("POST", path, token, body)
+			var out struct {
+				SessionID int64 `json:"sessionId"`
+			}
+			if rec.Code == http.StatusOK {
+				decode(t, rec, &out)
+			}
+			return rec.Code, out.SessionID
+		}
+	}
+	pause, resume := call("/api/time/pause"), call("/api/time/resume")
+
+	if code, id := pause(map[string]any{"sessionId": newer}); code != http.StatusOK || id != newer {
+		t.Fatalf("pause sessionId=newer: %d, session %d; want 200, %d", code, id, newer)
+	}
+	// A bare resume goes to the paused session, not the older running one.
+	if code, id := resume(nil); code != http.StatusOK || id != newer {
+		t.Fatalf("bare resume: %d, session %d; want 200, %d", code, id, newer)
+	}
+	if code, id := pause(map[string]any{"projectId": acme}); code != http.StatusOK || id != inAcme {
+		t.Fatalf("pause projectId: %d, session %d; want 200, %d", code, id, inAcme)
+	}
+	// A bare pause skips the paused one and takes the oldest running.
+	if code, id := pause(nil); code != http.StatusOK || id != oldest {
+		t.Fatalf("bare pause: %d, session %d; want 200, %d", code, id, oldest)
+	}
+	if code, _ := pause(map[string]any{"sessionId": oldest}); code != http.StatusConflict {
+		t.Errorf("pausing a paused session: %d, want 409", code)
+	}
+	if code, id := resume(map[string]any{"sessionId": inAcme}); code != http.StatusOK || id != inAcme {
+		t.Errorf("resume sessionId: %d, session %d; want 200, %d", code, id, inAcme)
+	}
+	if code, _ := resume(map[string]any{"sessionId": newer}); code != http.StatusConflict {
+		t.Errorf("resuming a running session: %d, want 409", code)
+	}
+
+	// Someone else's session doesn't match.
+	rec := e.do("POST", "/api/time/start", bobToken, nil)
+	wantStatus(t, rec, http.StatusCreated)
+	var bobs Session
+	decode(t, rec, &bobs)
+	if code, _ := pause(map[string]any{"sessionId": bobs.ID}); code != http.StatusNotFound {
+		t.Errorf("pausing another user's session: %d, want 404", code)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+)
+
+//
+// ───────────────────────────── User preferences ─────────────────────────────
+//
+
+// Preferences are per-user behavior toggles (all off by default).
+type Preferences struct {
+	AutoStartOnLogin bool   `json:"autoStartOnLogin"`
+	Timezone         string `json:"timezone"` // IANA name; "" = UTC (see timezone.go)
+}
+
+// preferencesPatch is the PATCH body; nil fields are left unchanged.
+type preferencesPatch struct {
+	AutoStartOnLogin *bool   `json:"autoStartOnLogin"`
+	Timezone         *string `json:"timezone"` // "" clears it
+}
+
+// GET /api/preferences
+// Returns the current preferences.
+func (s *Server) getPreferences(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+	s.writePreferences(w, uid)
+}
+
+// PATCH /api/preferences
+// Partial update; returns the new preferences.
+func (s *Server) patchPreferences(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	var req preferencesPatch
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if req.Timezone != nil && *req.Timezone != "" {
+		if _, err := loadTimezone(*req.Timezone); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+			return
+		}
+	}
+	if req.AutoStartOnLogin != nil {
+		if _, err := s.db.Exec(
+			`UPDATE users SET auto_start_on_login=$1 WHERE id=$2`,
+			*req.AutoStartOnLogin, uid,
+		); err != nil {
+			serverError(w, err)
+			return
+		}
+	}
+	if req.Timezone != nil {
+		if _, err := s.db.Exec(
+			`UPDATE users SET timezone=$1 WHERE id=$2`, *req.Timezone, uid,
+		); err != nil {
+			serverError(w, err)
+			return
+		}
+	}
+	s.writePreferences(w, uid)
+}
+
+func (s *Server) writePreferences(w http.ResponseWriter, uid int64) {
+	var p Preferences
+	if err := s.db.QueryRow(
+		`SELECT auto_start_on_login, timezone FROM users WHERE id=$1`, uid,
+	).Scan(&p.AutoStartOnLogin, &p.Timezone); err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
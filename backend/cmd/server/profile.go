@@ -0,0 +1,185 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//
+// ──────────────────────────────── Profile ───────────────────────────────────
+//
+
+// Profile is the "who am I" view of the authenticated user.
+type Profile struct {
+	ID            int64     `json:"id"`
+	Email         string    `json:"email"`
+	CreatedAt     time.Time `json:"createdAt"`
+	EmailVerified bool      `json:"emailVerified"`
+	PendingEmail  *string   `json:"pendingEmail,omitempty"` // awaiting confirmation
+	Timezone      string    `json:"timezone"`               // IANA name; "" = UTC
+}
+
+// profilePatch is the PATCH /auth/me body; nil fields are left unchanged.
+type profilePatch struct {
+	Email *string `json:"email"`
+}
+
+// Body for DELETE /auth/me.
+type deleteAccountReq struct {
+	Password string `json:"password"`
+}
+
+// GET /auth/me
+// Returns the caller's profile (lets a reloaded app rebuild its state).
+func (s *Server) getMe(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+	s.writeProfile(w, uid)
+}
+
+// PATCH /auth/me
+// {email}: change the address (see changeEmail); returns the new profile.
+func (s *Server) patchMe(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	var req profilePatch
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if req.Email != nil {
+		if err := s.changeEmail(r, uid, normalizeEmail(*req.Email)); err != nil {
+			writeErr(w, err)
+			return
+		}
+	}
+	s.writeProfile(w, uid)
+}
+
+func (s *Server) writeProfile(w http.ResponseWriter, uid int64) {
+	p, err := s.loadProfile(uid)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (s *Server) loadProfile(uid int64) (Profile, error) {
+	var p Profile
+	err := s.db.QueryRow(`
+		SELECT id, email, created_at, email_verified, pending_email, timezone
+		FROM users WHERE id=$1
+	`, uid).Scan(&p.ID, &p.Email, &p.CreatedAt, &p.EmailVerified, &p.PendingEmail, &p.Timezone)
+	return p, err
+}
+
+// changeEmail applies the register rules (normalized, valid, unique) to a
+// new address. With verification on, it only becomes pending and a link is
+// mailed to it; the old address keeps working for login until the link is
+// opened (see verifyEmail). Asking for the current address again cancels a
+// pending change.
+func (s *Server) changeEmail(r *http.Request, uid int64, email string) error {
+	if !validEmail(email) {
+		return badRequest(codeInvalidEmail, "email invalid")
+	}
+	var taken bool
+	if err := s.db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(email)=$1 AND id<>$2)`, email, uid,
+	).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return &apiError{http.StatusConflict, codeEmailTaken, "email already registered"}
+	}
+
+	var current string
+	if err := s.db.QueryRow(`SELECT email FROM users WHERE id=$1`, uid).Scan(&current); err != nil {
+		return err
+	}
+	if email == current {
+		_, err := s.db.Exec(`UPDATE users SET pending_email=NULL WHERE id=$1`, uid)
+		return err
+	}
+
+	if !s.requireVerification {
+		_, err := s.db.Exec(`UPDATE users SET email=$2, pending_email=NULL WHERE id=$1`, uid, email)
+		if isEmailTaken(err) {
+			return &apiError{http.StatusConflict, codeEmailTaken, "email already registered"}
+		}
+		return err
+	}
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`UPDATE users SET pending_email=$2 WHERE id=$1`, uid, email); err != nil {
+		return err
+	}
+	token, err := createVerification(tx, uid, email, s.clock.now())
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.sendVerification(r.Context(), email, token)
+	return nil
+}
+
+// DELETE /auth/me
+// {password}: erases the account after re-checking the password (401 if
+// wrong), 204. Everything goes in one transaction: sessions (and their
+// pauses), projects, tokens, the user's audit trail, then the user row,
+// whose remaining rows go with it via ON DELETE CASCADE. Nothing is kept or
+// anonymized, except the one account_deleted audit entry recording the erase
+// itself; this is the GDPR erase.
+func (s *Server) deleteMe(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	var req deleteAccountReq
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var hash string
+	if err := tx.QueryRow(`SELECT password_hash FROM users WHERE id=$1 FOR UPDATE`, uid).Scan(&hash); err != nil {
+		serverError(w, err)
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)) != nil {
+		writeError(w, http.StatusUnauthorized, codeInvalidCredentials, "invalid credentials")
+		return
+	}
+
+	for _, q := range []string{
+		`DELETE FROM sessions WHERE user_id=$1`,
+		`DELETE FROM projects WHERE user_id=$1`,
+		`DELETE FROM auth_tokens WHERE user_id=$1`,
+		`DELETE FROM refresh_tokens WHERE user_id=$1`,
+		`DELETE FROM audit_log WHERE user_id=$1`,
+		`DELETE FROM users WHERE id=$1`,
+	} {
+		if _, err := tx.Exec(q, uid); err != nil {
+			serverError(w, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+	s.audit(r, uid, eventAccountDeleted, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//
+// ───────────────────────────────── Projects API ─────────────────────────────
+//
+
+// Project groups sessions (e.g. per client). Always owned by one user.
+type Project struct {
+	ID              int64     `json:"id"`
+	Name            string    `json:"name"`
+	Color           string    `json:"color"`           // "#RRGGBB" or ""
+	HourlyRateCents *int64    `json:"hourlyRateCents"` // nil = non-billable
+	Currency        string    `json:"currency"`        // ISO 4217, e.g. "EUR"
+	RoundTo         *int      `json:"roundTo"`         // minutes; nil = ROUND_TO_MINUTES
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// projectCols is the column list scanProject expects, in order.
+const projectCols = `id, name, color, hourly_rate_cents, currency, round_to, created_at`
+
+// scanProject reads one row selected with projectCols.
+func scanProject(row rowScanner) (Project, error) {
+	var p Project
+	err := row.Scan(&p.ID, &p.Name, &p.Color, &p.HourlyRateCents, &p.Currency, &p.RoundTo, &p.CreatedAt)
+	return p, err
+}
+
+// Body for POST /api/projects and PATCH /api/projects/{id}.
+// On PATCH, nil fields are left unchanged; hourlyRateCents 0 removes the
+// rate and roundTo 0 goes back to ROUND_TO_MINUTES.
+type projectReq struct {
+	Name            *string `json:"name"`
+	Color           *string `json:"color"`
+	HourlyRateCents *int64  `json:"hourlyRateCents"`
+	Currency        *string `json:"currency"`
+	RoundTo         *int    `json:"roundTo"`
+}
+
+const maxProjectName = 100
+
+// defaultCurrency matches the projects.currency column default.
+const defaultCurrency = "EUR"
+
+var (
+	colorRe    = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	currencyRe = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// validate trims the name and checks both fields; create requires a name.
+func (p *projectReq) validate(create bool) error {
+	if p.Name != nil {
+		n := strings.TrimSpace(*p.Name)
+		p.Name = &n
+		if n == "" || len(n) > maxProjectName {
+			return errors.New("name must be 1-100 characters")
+		}
+	} else if create {
+		return errors.New("name is required")
+	}
+	if p.Color != nil && *p.Color != "" && !colorRe.MatchString(*p.Color) {
+		return errors.New("color must look like #RRGGBB")
+	}
+	if p.HourlyRateCents != nil && *p.HourlyRateCents < 0 {
+		return errors.New("hourlyRateCents must not be negative")
+	}
+	if p.Currency != nil {
+		c := strings.ToUpper(strings.TrimSpace(*p.Currency))
+		p.Currency = &c
+		if !currencyRe.MatchString(c) {
+			return errors.New("currency must be a 3-letter ISO 4217 code")
+		}
+	}
+	if p.RoundTo != nil && *p.RoundTo != 0 && !validRoundTo(*p.RoundTo) {
+		return errors.New("roundTo must divide 60 evenly")
+	}
+	return nil
+}
+
+// ownsProject reports whether project pid exists and belongs to uid.
+func (s *Server) ownsProject(uid, pid int64) (bool, error) {
+	var ok bool
+	err := s.db.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM projects WHERE id=$1 AND user_id=$2)`,
+		pid, uid,
+	).Scan(&ok)
+	return ok, err
+}
+
+// GET /api/projects
+// Returns the caller's projects, by name.
+func (s *Server) listProjects(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	rows, err := s.db.Query(`
+		SELECT `+projectCols+`
+		FROM projects
+		WHERE user_id=$1
+		ORDER BY name ASC, id ASC
+	`, uid)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	out := []Project{}
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			serverError(w, err)
+			return
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// POST /api/projects
+// Creates {name, color?, hourlyRateCents?, currency?, roundTo?}; 201 + Location.
+func (s *Server) createProject(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	var req projectReq
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := req.validate(true); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	color, currency := "", defaultCurrency
+	if req.Color != nil {
+		color = *req.Color
+	}
+	if req.Currency != nil {
+		currency = *req.Currency
+	}
+	p, err := scanProject(s.db.QueryRow(`
+		INSERT INTO projects(user_id, name, color, hourly_rate_cents, currency, round_to)
+		VALUES ($1,$2,$3,NULLIF($4::bigint, 0),$5,NULLIF($6::int, 0))
+		RETURNING `+projectCols,
+		uid, *req.Name, color, req.HourlyRateCents, currency, req.RoundTo,
+	))
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/api/projects/"+int64ToStr(p.ID))
+	writeJSON(w, http.StatusCreated, p)
+}
+
+// PATCH /api/projects/{id}
+// Partial update {name?, color?, hourlyRateCents?, currency?, roundTo?}.
+// Projects of other users are reported as 404. A new roundTo applies to
+// sessions stopped from now on; stored durations are not recomputed.
+func (s *Server) updateProject(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+	pid, err := strToInt64(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "bad project id")
+		return
+	}
+
+	var req projectReq
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := req.validate(false); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	p, err := scanProject(s.db.QueryRow(`
+		UPDATE projects
+		SET name=COALESCE($3, name), color=COALESCE($4, color),
+		    hourly_rate_cents=CASE WHEN $5::bigint IS NULL THEN hourly_rate_cents ELSE NULLIF($5, 0) END,
+		    currency=COALESCE($6, currency),
+		    round_to=CASE WHEN $7::int IS NULL THEN round_to ELSE NULLIF($7, 0) END
+		WHERE id=$1 AND user_id=$2
+		RETURNING `+projectCols,
+		pid, uid, req.Name, req.Color, req.HourlyRateCents, req.Currency, req.RoundTo))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, codeProjectNotFound, "project not found")
+		return
+	}
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// DELETE /api/projects/{id}
+// 204; its sessions become unassigned. Other users' projects are 404.
+func (s *Server) deleteProject(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+	pid, err := strToInt64(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "bad project id")
+		return
+	}
+
+	res, err := s.db.Exec(`DELETE FROM projects WHERE id=$1 AND user_id=$2`, pid, uid)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, http.StatusNotFound, codeProjectNotFound, "project not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Body for POST /api/projects/{id}/transfer.
+type transferReq struct {
+	ToProjectID  int64 `json:"toProjectId"`
+	DeleteSource bool  `json:"deleteSource"` // drop the emptied source project too
+}
+
+// POST /api/projects/{id}/transfer
+// Moves every session of project {id} (trashed and archived ones included)
+// to {toProjectId}, optionally deleting the source afterwards, all in one
+// transaction. Both must be the caller's projects, else 404. Returns
+// {moved, sourceDeleted}.
+func (s *Server) transferProject(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+	from, err := strToInt64(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "bad project id")
+		return
+	}
+
+	var req transferReq
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if req.ToProjectID <= 0 {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "toProjectId is required")
+		return
+	}
+	if req.ToProjectID == from {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "toProjectId must differ from the source project")
+		return
+	}
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock both rows so neither project can be deleted mid-transfer.
+	var owned int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM (
+		  SELECT id FROM projects WHERE id IN ($1,$2) AND user_id=$3 FOR UPDATE
+		) p
+	`, from, req.ToProjectID, uid).Scan(&owned); err != nil {
+		serverError(w, err)
+		return
+	}
+	if owned != 2 {
+		writeError(w, http.StatusNotFound, codeProjectNotFound, "project not found")
+		return
+	}
+
+	res, err := tx.Exec(
+		`UPDATE sessions SET project_id=$1 WHERE project_id=$2 AND user_id=$3`,
+		req.ToProjectID, from, uid)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	moved, _ := res.RowsAffected()
+
+	if req.DeleteSource {
+		if _, err := tx.Exec(`DELETE FROM projects WHERE id=$1 AND user_id=$2`, from, uid); err != nil {
+			serverError(w, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+
+	s.audit(r, uid, eventProjectTransfer, map[string]any{
+		"from": from, "to": req.ToProjectID, "moved": moved, "sourceDeleted": req.DeleteSource,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"moved":         moved,
+		"sourceDeleted": req.DeleteSource,
+	})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTransferProject(t *testing.T) {
+	e := newTestEnv(t)
+	_, token := e.user("alice@example.com")
+	_, bobToken := e.user("bob@example.com")
+
+	from := e.project(token, map[string]any{"name": "Acme"}).ID
+	to := e.project(token, map[string]any{"name": "Acme Group"}).ID
+	bobs := e.project(bobToken, map[string]any{"name": "Bob's"}).ID
+
+	for i := 0; i < 3; i++ {
+		start := testStart.Add(-time.Duration(i+2) * time.Hour)
+		wantStatus(t, e.do("POST", "/api/time/manual", token, map[string]any{
+			"startTime": start, "endTime": start.Add(time.Hour), "projectId": from,
+		}), http.StatusCreated)
+	}
+
+	// Someone else's project is "not found" on either side.
+	wantStatus(t, e.do("POST", fmt.Sprintf("/api/projects/%d/transfer", from), token,
+		map[string]any{"toProjectId": bobs}), http.StatusNotFound)
+	wantStatus(t, e.do("POST", fmt.Sprintf("/api/projects/%d/transfer", bobs), token,
+		map[string]any{"toProjectId": to}), http.StatusNotFound)
+	wantStatus(t, e.do("POST", fmt.Sprintf("/api/projects/%d/transfer", from), token,
+		map[string]any{"toProjectId": from}), http.StatusBadRequest)
+
+	rec := e.do("POST", fmt.Sprintf("/api/projects/%d/transfer", from), token,
+		map[string]any{"toProjectId": to, "deleteSource": true})
+	wantStatus(t, rec, http.StatusOK)
+	var out struct {
+		Moved         int64 `json:"moved"`
+		SourceDeleted bool  `json:"sourceDeleted"`
+	}
+	decode(t, rec, &out)
+	if out.Moved != 3 || !out.SourceDeleted {
+		t.Fatalf("transfer = %+v, want 3 moved and source deleted", out)
+	}
+
+	var left, moved int
+	if err := e.s.db.QueryRow(`
+		SELECT COUNT(*) FILTER (WHERE project_id=$1), COUNT(*) FILTER (WHERE project_id=$2)
+		FROM sessions`, from, to).Scan(&left, &moved); err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 || moved != 3 {
+		t.Errorf("sessions left on source %d, on target %d; want 0 and 3", left, moved)
+	}
+	var exists bool
+	if err := e.s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM projects WHERE id=$1)`, from).Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Error("source project still exists after deleteSource")
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+//
+// ──────────────────────────────── Rate limiting ─────────────────────────────
+//
+
+// limiterSet is a map of token buckets keyed by client (IP or email).
+// Idle buckets are evicted by sweep so the map can't grow without bound.
+type limiterSet struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	limit     rate.Limit
+	burst     int
+	perMinute int // as configured, for GET /api/settings
+}
+
+type bucket struct {
+	lim  *rate.Limiter
+	seen time.Time
+}
+
+// newLimiterSet allows perMinute requests per key, with bursts up to perMinute.
+func newLimiterSet(perMinute int) *limiterSet {
+	return &limiterSet{
+		buckets:   map[string]*bucket{},
+		limit:     rate.Limit(float64(perMinute) / 60),
+		burst:     perMinute,
+		perMinute: perMinute,
+	}
+}
+
+// allow takes a token for key at now. If none is available it returns
+// false and how long until the next one.
+func (l *limiterSet) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{lim: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.seen = now
+	l.mu.Unlock()
+
+	res := b.lim.ReserveN(now, 1)
+	if d := res.DelayFrom(now); d > 0 {
+		res.CancelAt(now)
+		return false, d
+	}
+	return true, 0
+}
+
+// sweep drops buckets not used for longer than idle before now.
+func (l *limiterSet) sweep(idle time.Duration, now time.Time) {
+	cutoff := now.Add(-idle)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for k, b := range l.buckets {
+		if b.seen.Before(cutoff) {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// authLimiter throttles the credential endpoints per client IP and per email.
+type authLimiter struct {
+	byIP    *limiterSet
+	byEmail *limiterSet
+}
+
+func newAuthLimiter(ipPerMinute, emailPerMinute int) *authLimiter {
+	return &authLimiter{byIP: newLimiterSet(ipPerMinute), byEmail: newLimiterSet(emailPerMinute)}
+}
+
+// janitor evicts idle buckets every interval until ctx is done.
+func (a *authLimiter) janitor(ctx context.Context, clock Clock, every, idle time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			a.byIP.sweep(idle, clock.now())
+			a.byEmail.sweep(idle, clock.now())
+		}
+	}
+}
+
+// rateLimited wraps /auth/login and /auth/register. Over the limit it
+// returns 429 with Retry-After (seconds). The body is peeked for the email
+// and restored for the handler.
+func (s *Server) rateLimited(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !throttle(w, s.authLimits.byIP, clientIP(r), s.clock.now()) {
+			return
+		}
+
+		body, err := io.ReadAll(r.Body) // bounded by limitBody
+		if err != nil {
+			writeErr(w, bodyError(err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		var peek struct {
+			Email string `json:"email"`
+		}
+		if json.Unmarshal(body, &peek) == nil && peek.Email != "" {
+			if !throttle(w, s.authLimits.byEmail, normalizeEmail(peek.Email), s.clock.now()) {
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// throttle takes a token for key, or replies 429 and returns false.
+func throttle(w http.ResponseWriter, set *limiterSet, key string, now time.Time) bool {
+	ok, wait := set.allow(key, now)
+	if !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeError(w, http.StatusTooManyRequests, codeRateLimited, "too many attempts, slow down")
+	}
+	return ok
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Many goroutines hammering one key at one instant get exactly the burst.
+func TestLimiterSetHammer(t *testing.T) {
+	const perMinute, workers, perWorker = 10, 50, 20
+	l := newLimiterSet(perMinute)
+	now := testStart
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				if ok, _ := l.allow("203.0.113.7", now); ok {
+					allowed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := allowed.Load(); n != perMinute {
+		t.Fatalf("allowed %d of %d requests, want exactly %d", n, workers*perWorker, perMinute)
+	}
+
+	// Refused requests don't consume tokens: one comes back 6s later.
+	ok, wait := l.allow("203.0.113.7", now)
+	if ok || wait <= 0 || wait > 6*time.Second {
+		t.Fatalf("over limit: ok=%v wait=%v, want refusal with wait in (0, 6s]", ok, wait)
+	}
+	if ok, _ := l.allow("203.0.113.7", now.Add(6*time.Second)); !ok {
+		t.Error("no token after the refill interval")
+	}
+	// Other keys have their own bucket.
+	if ok, _ := l.allow("198.51.100.1", now); !ok {
+		t.Error("a fresh key was throttled")
+	}
+}
+
+func TestLimiterSetSweep(t *testing.T) {
+	l := newLimiterSet(10)
+	l.allow("old", testStart)
+	l.allow("new", testStart.Add(9*time.Minute))
+	l.sweep(5*time.Minute, testStart.Add(10*time.Minute))
+	if _, ok := l.buckets["old"]; ok {
+		t.Error("idle bucket survived the sweep")
+	}
+	if _, ok := l.buckets["new"]; !ok {
+		t.Error("recent bucket was swept")
+	}
+}
+
+func TestRateLimitedByEmail(t *testing.T) {
+	cfg := testConfig()
+	cfg.AuthRateIPPerMinute = 100
+	cfg.AuthRateEmailPerMinute = 3
+	s := newServer(cfg, nil)
+	clock := newFakeClock(testStart)
+	s.clock = clock
+
+	h := s.rateLimited(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	try := func(email, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/auth/login", strings.NewReader(`{"email":"`+email+`","password":"x"}`))
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	// Case and spacing don't dodge the per-email bucket, nor do new IPs.
+	for i, email := range []string{"alice@example.com", "Alice@Example.com", " ALICE@example.com"} {
+		if rec := try(email, "192.0.2."+strconv.Itoa(i+1)); rec.Code != http.StatusNoContent {
+			t.Fatalf("attempt %d: status %d, want 204", i+1, rec.Code)
+		}
+	}
+	rec := try("alice@example.com", "192.0.2.9")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("4th attempt: status %d, want 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "20" {
+		t.Errorf("Retry-After = %q, want 20", rec.Header().Get("Retry-After"))
+	}
+
+	clock.advance(20 * time.Second)
+	if rec := try("alice@example.com", "192.0.2.9"); rec.Code != http.StatusNoContent {
+		t.Errorf("after Retry-After: status %d, want 204", rec.Code)
+	}
+}
@@ -0,0 +1,570 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//
+// ─────────────────────────────── Reports API ────────────────────────────────
+//
+
+// Invoice is shaped one-to-one for a client-side PDF template.
+type Invoice struct {
+	Header   InvoiceHeader `json:"header"`
+	Lines    []InvoiceLine `json:"lines"`
+	Totals   InvoiceTotals `json:"totals"`
+	Rounding *Rounding     `json:"rounding,omitempty"` // set with ?rounding= / ?roundTo=
+}
+
+type InvoiceHeader struct {
+	UserID  int64     `json:"userId"`
+	Email   string    `json:"email"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"` // exclusive
+	Project *Project  `json:"project,omitempty"`
+}
+
+type InvoiceLine struct {
+	Date            string    `json:"date"` // YYYY-MM-DD in the user's zone
+	StartTime       time.Time `json:"startTime"`
+	EndTime         time.Time `json:"endTime"`
+	DurationSeconds int       `json:"durationSeconds"`
+	DurationMinutes int       `json:"durationMinutes"`
+	RoundedMinutes  int       `json:"roundedMinutes"`
+	Note            string    `json:"note"`
+}
+
+// InvoiceTotals.Minutes is floor(Seconds / 60), not the sum of line minutes,
+// so many short sessions don't lose their remainders. AmountCents prices
+// RoundedMinutes at the project's hourly rate, in header.project.currency;
+// it is only set on a ?projectId= invoice whose project has a rate.
+type InvoiceTotals struct {
+	Seconds        int    `json:"seconds"`
+	Minutes        int    `json:"minutes"`
+	RoundedMinutes int    `json:"roundedMinutes"`
+	AmountCents    *int64 `json:"amountCents,omitempty"`
+}
+
+// GET /api/time/invoice-data?from=&to=[&projectId=&rounding=&roundTo=]
+// Returns finished sessions in the range (optionally of one project) as
+// invoice header, lines and totals. With rounding/roundTo, roundedMinutes
+// are recomputed per line (see rounding.go) instead of the stored ones.
+// For a billable project the totals include amountCents.
+func (s *Server) invoiceData(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	loc, err := s.userLocation(r, uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	rng, err := parseDateRange(r.URL.Query(), loc)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	rd, err := parseRounding(r.URL.Query(), s.roundTo)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	inv := Invoice{
+		Header:   InvoiceHeader{UserID: uid, From: rng.From, To: rng.To},
+		Lines:    []InvoiceLine{},
+		Rounding: rd,
+	}
+	if err := s.db.QueryRow(
+		`SELECT email FROM users WHERE id=$1`, uid,
+	).Scan(&inv.Header.Email); err != nil {
+		serverError(w, err)
+		return
+	}
+
+	var projectID *int64
+	if v := r.URL.Query().Get("projectId"); v != "" {
+		pid, err := strToInt64(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "bad projectId")
+			return
+		}
+		p, err := scanProject(s.db.QueryRow(
+			`SELECT `+projectCols+` FROM projects WHERE id=$1 AND user_id=$2`,
+			pid, uid,
+		))
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, codeProjectNotFound, "project not found")
+			return
+		}
+		if err != nil {
+			serverError(w, err)
+			return
+		}
+		projectID, inv.Header.Project = &p.ID, &p
+	}
+
+	rows, err := s.db.Query(`
+		SELECT start_time, end_time, COALESCE(duration_seconds, duration_minutes * 60), duration_minutes,
+		       COALESCE(rounded_minutes, duration_minutes), note
+		FROM sessions
+		WHERE user_id=$1 AND start_time >= $2 AND start_time < $3
+		  AND ($4::bigint IS NULL OR project_id = $4)
+		  AND end_time IS NOT NULL AND deleted_at IS NULL
+		ORDER BY start_time ASC, id ASC
+	`, uid, rng.From, rng.To, projectID)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l InvoiceLine
+		if err := rows.Scan(&l.StartTime, &l.EndTime, &l.DurationSeconds, &l.DurationMinutes, &l.RoundedMinutes, &l.Note); err != nil {
+			serverError(w, err)
+			return
+		}
+		l.Date = l.StartTime.In(loc).Format(time.DateOnly)
+		if rd != nil {
+			l.RoundedMinutes = int(rd.minutes(int64(l.DurationSeconds)))
+		}
+		inv.Totals.Seconds += l.DurationSeconds
+		inv.Totals.RoundedMinutes += l.RoundedMinutes
+		inv.Lines = append(inv.Lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, err)
+		return
+	}
+	inv.Totals.Minutes = inv.Totals.Seconds / 60
+	if p := inv.Header.Project; p != nil && p.HourlyRateCents != nil {
+		amount := amountCents(int64(inv.Totals.RoundedMinutes), *p.HourlyRateCents)
+		inv.Totals.AmountCents = &amount
+	}
+
+	writeJSON(w, http.StatusOK, inv)
+}
+
+// ProjectTotal is one row of totals-by-project; nil ID/name = no project.
+type ProjectTotal struct {
+	ProjectID    *int64  `json:"projectId"`
+	ProjectName  *string `json:"projectName"`
+	TotalMinutes int64   `json:"totalMinutes"`
+}
+
+// GET /api/time/totals-by-project?from=&to=
+// Sums finished session time per project in the range, in whole minutes. Sessions without
+// a project are bucketed under projectId=null.
+func (s *Server) totalsByProject(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	loc, err := s.userLocation(r, uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	rng, err := parseDateRange(r.URL.Query(), loc)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	rows, err := s.db.Query(`
+		SELECT s.project_id, p.name, COALESCE(SUM(COALESCE(s.duration_seconds, s.duration_minutes * 60)), 0) / 60
+		FROM sessions s
+		LEFT JOIN projects p ON p.id = s.project_id
+		WHERE s.user_id=$1 AND s.start_time >= $2 AND s.start_time < $3
+		  AND s.deleted_at IS NULL
+		GROUP BY s.project_id, p.name
+		ORDER BY p.name ASC NULLS LAST
+	`, uid, rng.From, rng.To)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	out := []ProjectTotal{}
+	for rows.Next() {
+		var t ProjectTotal
+		if err := rows.Scan(&t.ProjectID, &t.ProjectName, &t.TotalMinutes); err != nil {
+			serverError(w, err)
+			return
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+//
+// ─────────────────────────────── Period totals ──────────────────────────────
+//
+
+// PeriodTotal is the response of total-week / total-month. Days covers the
+// whole period, zero-filled, oldest first, ready for a bar chart. The goal
+// fields appear only for the week, and only when a weekly goal is set.
+type PeriodTotal struct {
+	From         time.Time  `json:"from"`
+	To           time.Time  `json:"to"` // exclusive
+	TotalSeconds int64      `json:"totalSeconds"`
+	TotalMinutes int64      `json:"totalMinutes"`
+	Days         []DayTotal `json:"days"`
+	*GoalProgress
+}
+
+type DayTotal struct {
+	Date    string `json:"date"` // YYYY-MM-DD in the user's zone
+	Seconds int64  `json:"seconds"`
+	Minutes int64  `json:"minutes"`
+}
+
+// isoWeek is the Monday-to-Monday week containing now, in loc.
+func isoWeek(now time.Time, loc *time.Location) dateRange {
+	day := today(now, loc)
+	offset := (int(day.From.Weekday()) + 6) % 7 // days since Monday
+	from := day.From.AddDate(0, 0, -offset)
+	return dateRange{From: from, To: from.AddDate(0, 0, 7)}
+}
+
+// calendarMonth is the month containing now, in loc.
+func calendarMonth(now time.Time, loc *time.Location) dateRange {
+	y, m, _ := now.In(loc).Date()
+	from := time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	return dateRange{From: from, To: from.AddDate(0, 1, 0)}
+}
+
+// GET /api/time/total-week[?tz=]
+// Finished time in the current ISO week (Monday first), per day, plus
+// progress against the weekly goal if one is set.
+func (s *Server) totalWeek(w http.ResponseWriter, r *http.Request) {
+	s.periodTotal(w, r, isoWeek, "week")
+}
+
+// GET /api/time/total-month[?tz=]
+// Finished time in the current calendar month, per day.
+func (s *Server) totalMonth(w http.ResponseWriter, r *http.Request) {
+	s.periodTotal(w, r, calendarMonth, "")
+}
+
+// periodTotal serves a per-day breakdown of period; goal names the goals
+// period to report progress against ("" for none).
+func (s *Server) periodTotal(w http.ResponseWriter, r *http.Request, period func(time.Time, *time.Location) dateRange, goal string) {
+	uid, _ := userIDFromCtx(r)
+
+	loc, err := s.userLocation(r, uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	rng := period(s.clock.now(), loc)
+
+	rows, err := s.db.Query(`
+		SELECT to_char((start_time AT TIME ZONE $4)::date, 'YYYY-MM-DD'),
+		       SUM(COALESCE(duration_seconds, duration_minutes * 60))
+		FROM sessions
+		WHERE user_id=$1 AND start_time >= $2 AND start_time < $3
+		  AND end_time IS NOT NULL AND deleted_at IS NULL
+		GROUP BY 1
+	`, uid, rng.From, rng.To, loc.String())
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	perDay := map[string]int64{}
+	for rows.Next() {
+		var date string
+		var secs int64
+		if err := rows.Scan(&date, &secs); err != nil {
+			serverError(w, err)
+			return
+		}
+		perDay[date] = secs
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, err)
+		return
+	}
+
+	out := PeriodTotal{From: rng.From, To: rng.To, Days: []DayTotal{}}
+	for d := rng.From; d.Before(rng.To); d = d.AddDate(0, 0, 1) {
+		date := d.Format(time.DateOnly)
+		secs := perDay[date]
+		out.Days = append(out.Days, DayTotal{Date: date, Seconds: secs, Minutes: secs / 60})
+		out.TotalSeconds += secs
+	}
+	out.TotalMinutes = out.TotalSeconds / 60
+	if goal != "" {
+		if out.GoalProgress, err = s.goalProgress(uid, goal, out.TotalMinutes); err != nil {
+			serverError(w, err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+//
+// ───────────────────────────────── Heatmap ──────────────────────────────────
+//
+
+// HeatmapDay is one active day of GET /api/time/heatmap.
+type HeatmapDay struct {
+	Date         string `json:"date"` // YYYY-MM-DD in the user's zone
+	TotalMinutes int64  `json:"totalMinutes"`
+}
+
+// GET /api/time/heatmap[?year=&tz=]
+// Finished time per day for a calendar year (default: the current one),
+// listing only days with activity, oldest first. Days are cut at local
+// midnight, so a year grid lines up with what the user saw on their clock.
+func (s *Server) heatmap(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	loc, err := s.userLocation(r, uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	year := s.clock.now().In(loc).Year()
+	if v := r.URL.Query().Get("year"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1970 || n > 9999 {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "year must be between 1970 and 9999")
+			return
+		}
+		year = n
+	}
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+
+	rows, err := s.db.Query(`
+		SELECT to_char((start_time AT TIME ZONE $4)::date, 'YYYY-MM-DD'),
+		       SUM(COALESCE(duration_seconds, duration_minutes * 60))
+		FROM sessions
+		WHERE user_id=$1 AND start_time >= $2 AND start_time < $3
+		  AND end_time IS NOT NULL AND deleted_at IS NULL
+		GROUP BY 1
+		ORDER BY 1
+	`, uid, from, from.AddDate(1, 0, 0), loc.String())
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	out := []HeatmapDay{}
+	for rows.Next() {
+		var d HeatmapDay
+		var secs int64
+		if err := rows.Scan(&d.Date, &secs); err != nil {
+			serverError(w, err)
+			return
+		}
+		d.TotalMinutes = secs / 60
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+//
+// ───────────────────────────────── Earnings ─────────────────────────────────
+//
+
+// Earnings is the response of GET /api/time/earnings. Totals has one entry
+// per currency, since amounts in different currencies can't be added up.
+type Earnings struct {
+	From               time.Time         `json:"from"`
+	To                 time.Time         `json:"to"` // exclusive
+	Projects           []ProjectEarnings `json:"projects"`
+	Totals             []EarningsTotal   `json:"totals"`
+	NonBillableMinutes int64             `json:"nonBillableMinutes"`
+	Rounding           *Rounding         `json:"rounding,omitempty"` // set with ?rounding= / ?roundTo=
+}
+
+// ProjectEarnings is one project's billed time; nil ID/name = no project.
+// Non-billable rows have no rate, currency or amount.
+type ProjectEarnings struct {
+	ProjectID       *int64  `json:"projectId"`
+	ProjectName     *string `json:"projectName"`
+	Minutes         int64   `json:"minutes"`
+	Billable        bool    `json:"billable"`
+	HourlyRateCents *int64  `json:"hourlyRateCents,omitempty"`
+	Currency        string  `json:"currency,omitempty"`
+	AmountCents     *int64  `json:"amountCents,omitempty"`
+}
+
+type EarningsTotal struct {
+	Currency    string `json:"currency"`
+	Minutes     int64  `json:"minutes"`
+	AmountCents int64  `json:"amountCents"`
+}
+
+// amountCents prices minutes at an hourly rate, in integer cents rounded
+// half up. It is applied to a project's summed minutes, not per session, so
+// rounding happens once.
+func amountCents(minutes, hourlyRateCents int64) int64 {
+	return (minutes*hourlyRateCents + 30) / 60
+}
+
+// GET /api/time/earnings?from=&to=[&rounding=&roundTo=]
+// Bills finished sessions in the range per project, using the rounded
+// minutes (as on the invoice) times the project's hourly rate. With
+// rounding/roundTo each session is re-rounded before summing (rounding.go).
+func (s *Server) earnings(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	loc, err := s.userLocation(r, uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	rng, err := parseDateRange(r.URL.Query(), loc)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	rd, err := parseRounding(r.URL.Query(), s.roundTo)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	minutes := "COALESCE(s.rounded_minutes, s.duration_minutes)"
+	if rd != nil {
+		minutes = rd.sql("COALESCE(s.duration_seconds, s.duration_minutes * 60)")
+	}
+
+	rows, err := s.db.Query(`
+		SELECT s.project_id, p.name, p.hourly_rate_cents, p.currency,
+		       SUM(`+minutes+`)
+		FROM sessions s
+		LEFT JOIN projects p ON p.id = s.project_id
+		WHERE s.user_id=$1 AND s.start_time >= $2 AND s.start_time < $3
+		  AND s.end_time IS NOT NULL AND s.deleted_at IS NULL
+		GROUP BY s.project_id, p.name, p.hourly_rate_cents, p.currency
+		ORDER BY p.name ASC NULLS LAST
+	`, uid, rng.From, rng.To)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	out := Earnings{From: rng.From, To: rng.To, Projects: []ProjectEarnings{}, Totals: []EarningsTotal{}, Rounding: rd}
+	totalIdx := map[string]int{} // currency -> index in out.Totals
+	for rows.Next() {
+		var pe ProjectEarnings
+		var currency sql.NullString
+		if err := rows.Scan(&pe.ProjectID, &pe.ProjectName, &pe.HourlyRateCents, &currency, &pe.Minutes); err != nil {
+			serverError(w, err)
+			return
+		}
+		if pe.HourlyRateCents == nil {
+			out.NonBillableMinutes += pe.Minutes
+			out.Projects = append(out.Projects, pe)
+			continue
+		}
+		amount := amountCents(pe.Minutes, *pe.HourlyRateCents)
+		pe.Billable, pe.Currency, pe.AmountCents = true, currency.String, &amount
+		out.Projects = append(out.Projects, pe)
+
+		i, ok := totalIdx[pe.Currency]
+		if !ok {
+			i = len(out.Totals)
+			totalIdx[pe.Currency] = i
+			out.Totals = append(out.Totals, EarningsTotal{Currency: pe.Currency})
+		}
+		out.Totals[i].Minutes += pe.Minutes
+		out.Totals[i].AmountCents += amount
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+//
+// ──────────────────────────────── Dashboard ─────────────────────────────────
+//
+
+// Summary is everything the home screen shows, in one response. Totals
+// count finished sessions only (like total-today / total-week); the running
+// one is in CurrentSession.
+type Summary struct {
+	CurrentSession      *CurrentSession `json:"currentSession"` // null when nothing runs
+	TodayTotalMinutes   int64           `json:"todayTotalMinutes"`
+	WeekTotalMinutes    int64           `json:"weekTotalMinutes"`
+	SessionCountToday   int             `json:"sessionCountToday"` // incl. a running one
+	LongestSessionToday *Session        `json:"longestSessionToday"`
+}
+
+// GET /api/time/summary[?tz=]
+// Dashboard stats for today and the current ISO week in the user's zone.
+func (s *Server) summary(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	loc, err := s.userLocation(r, uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	now := s.clock.now()
+	day, week := today(now, loc), isoWeek(now, loc)
+
+	var out Summary
+	if out.CurrentSession, err = s.loadCurrent(uid, now); err != nil {
+		serverError(w, err)
+		return
+	}
+
+	// Today lies inside the week, so one pass over the week covers both.
+	var todaySecs, weekSecs int64
+	if err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(COALESCE(duration_seconds, duration_minutes * 60))
+		                  FILTER (WHERE end_time IS NOT NULL AND start_time >= $4 AND start_time < $5), 0),
+		       COALESCE(SUM(COALESCE(duration_seconds, duration_minutes * 60))
+		                  FILTER (WHERE end_time IS NOT NULL), 0),
+		       COUNT(*) FILTER (WHERE start_time >= $4 AND start_time < $5)
+		FROM sessions
+		WHERE user_id=$1 AND start_time >= $2 AND start_time < $3 AND deleted_at IS NULL
+	`, uid, week.From, week.To, day.From, day.To).Scan(&todaySecs, &weekSecs, &out.SessionCountToday); err != nil {
+		serverError(w, err)
+		return
+	}
+	out.TodayTotalMinutes, out.WeekTotalMinutes = todaySecs/60, weekSecs/60
+
+	longest, err := scanSession(s.db.QueryRow(`
+		SELECT `+sessionCols+`
+		FROM sessions
+		WHERE user_id=$1 AND start_time >= $2 AND start_time < $3
+		  AND end_time IS NOT NULL AND deleted_at IS NULL
+		ORDER BY COALESCE(duration_seconds, duration_minutes * 60) DESC, start_time ASC
+		LIMIT 1
+	`, uid, day.From, day.To))
+	switch {
+	case err == nil:
+		out.LongestSessionToday = &longest
+	case !errors.Is(err, sql.ErrNoRows):
+		serverError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
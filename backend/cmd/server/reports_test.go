@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestInvoiceAmount(t *testing.T) {
+	e := newTestEnv(t)
+	_, token := e.user("alice@example.com")
+
+	billable := e.project(token, map[string]any{"name": "Acme", "hourlyRateCents": 6000}).ID
+	internal := e.project(token, map[string]any{"name": "Internal"}).ID
+
+	start := testStart.Add(-3 * time.Hour)
+	for _, pid := range []int64{billable, internal} {
+		wantStatus(t, e.do("POST", "/api/time/manual", token, map[string]any{
+			"startTime": start, "endTime": start.Add(90 * time.Minute), "projectId": pid,
+		}), http.StatusCreated)
+	}
+
+	invoice := func(pid int64) InvoiceTotals {
+		rec := e.do("GET", fmt.Sprintf("/api/time/invoice-data?from=2024-03-04&to=2024-03-04&projectId=%d", pid), token, nil)
+		wantStatus(t, rec, http.StatusOK)
+		var inv Invoice
+		decode(t, rec, &inv)
+		return inv.Totals
+	}
+	if got := invoice(billable); got.AmountCents == nil || *got.AmountCents != 9000 {
+		t.Errorf("billable amountCents = %v, want 9000 (90 min at 60.00/h)", got.AmountCents)
+	}
+	if got := invoice(internal); got.AmountCents != nil {
+		t.Errorf("non-billable amountCents = %d, want omitted", *got.AmountCents)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+//
+// ─────────────────────────────── Request bodies ─────────────────────────────
+//
+
+// limitBody caps every request body at max bytes (MAX_BODY_BYTES). Reading
+// past it fails with *http.MaxBytesError, which decodeJSON reports as 413.
+func limitBody(max int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// decodeJSON reads a required JSON body into v. Unknown fields are rejected
+// so a typo ("pasword") is a 400, not a silently ignored value; an
+// over-size body is a 413.
+func decodeJSON(r *http.Request, v any) error {
+	err := decodeOptionalJSON(r, v)
+	if errors.Is(err, io.EOF) {
+		return badRequest(codeBadJSON, "request body required")
+	}
+	return err
+}
+
+// decodeOptionalJSON is decodeJSON for endpoints whose body may be empty;
+// then v is left untouched and io.EOF is returned (callers may ignore it).
+func decodeOptionalJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil || errors.Is(err, io.EOF) {
+		return err
+	}
+	return bodyError(err)
+}
+
+// bodyError maps a failed body read or decode to its apiError.
+func bodyError(err error) error {
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		return &apiError{http.StatusRequestEntityTooLarge, codeBodyTooLarge,
+			fmt.Sprintf("request body exceeds %d bytes", tooBig.Limit)}
+	}
+	return badRequest(codeBadJSON, "bad json: "+err.Error())
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// decodeThrough runs body through limitBody(max) and decodeJSON, the way
+// every handler sees it, and returns the decode error as an HTTP response.
+func decodeThrough(t *testing.T, max int64, body string) (*httptest.ResponseRecorder, projectReq) {
+	t.Helper()
+	var got projectReq
+	h := limitBody(max, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := decodeJSON(r, &got); err != nil {
+			writeErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+	return rec, got
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Error errorBody `json:"error"`
+	}
+	decode(t, rec, &body)
+	return body.Error.Code
+}
+
+func TestDecodeJSONTooLarge(t *testing.T) {
+	body := `{"name":"` + strings.Repeat("a", 200) + `"}`
+	rec, _ := decodeThrough(t, 64, body)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", rec.Code)
+	}
+	if c := errorCode(t, rec); c != codeBodyTooLarge {
+		t.Errorf("code = %q, want %q", c, codeBodyTooLarge)
+	}
+
+	// At the limit is still fine.
+	body = `{"name":"` + strings.Repeat("a", 64-len(`{"name":""}`)) + `"}`
+	if rec, got := decodeThrough(t, 64, body); rec.Code != http.StatusNoContent || got.Name == nil {
+		t.Errorf("body of exactly the limit: status %d", rec.Code)
+	}
+}
+
+func TestDecodeJSONUnknownField(t *testing.T) {
+	rec, _ := decodeThrough(t, 1<<20, `{"name":"Acme","colour":"#ff0000"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	if c := errorCode(t, rec); c != codeBadJSON {
+		t.Errorf("code = %q, want %q", c, codeBadJSON)
+	}
+	if !strings.Contains(rec.Body.String(), "colour") {
+		t.Errorf("error doesn't name the unknown field: %s", rec.Body.String())
+	}
+}
+
+func TestDecodeJSONEmpty(t *testing.T) {
+	rec, _ := decodeThrough(t, 1<<20, "")
+	if rec.Code != http.StatusBadRequest || errorCode(t, rec) != codeBadJSON {
+		t.Errorf("empty body: %d %s, want 400 bad_json", rec.Code, rec.Body.String())
+	}
+
+	// decodeOptionalJSON lets the caller tell "no body" apart.
+	r := httptest.NewRequest("POST", "/", strings.NewReader(""))
+	var v stopReq
+	if err := decodeOptionalJSON(r, &v); !errors.Is(err, io.EOF) {
+		t.Errorf("decodeOptionalJSON(empty) = %v, want io.EOF", err)
+	}
+}
+
+// The same limits hold on a real route with the configured MAX_BODY_BYTES.
+func TestBodyLimitsOnRoute(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxBodyBytes = 256
+	e := newTestEnvConfig(t, cfg)
+	_, token := e.user("alice@example.com")
+
+	rec := e.do("POST", "/api/projects", token, `{"name":"`+strings.Repeat("a", 300)+`"}`)
+	wantStatus(t, rec, http.StatusRequestEntityTooLarge)
+	rec = e.do("POST", "/api/projects", token, `{"name":"Acme","owner":"bob"}`)
+	wantStatus(t, rec, http.StatusBadRequest)
+	if c := errorCode(t, rec); c != codeBadJSON {
+		t.Errorf("code = %q, want %q", c, codeBadJSON)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+//
+// ───────────────────────────── Duration rounding ────────────────────────────
+//
+// Sessions keep the raw duration_seconds (plus duration_minutes derived from
+// it) and additionally store rounded_minutes for billing. The increment is chosen by precedence:
+//
+//	per-session {roundTo} on stop  >  the project's roundTo  >  global ROUND_TO_MINUTES
+//
+// Every path that stores a duration (stop, stop-all, clear-today, auto-stop,
+// manual and quick-log entries, edits, imports) resolves it through
+// projectRoundTo; only POST /api/time/stop accepts a per-session value.
+//
+// Stored rounding is always up to the next full increment (billing
+// convention): with roundTo=15, 1..15 → 15, 16..30 → 30; 0 stays 0. Reports
+// can pick another mode per request (see Rounding below).
+
+// validRoundTo reports whether n is a sensible increment, i.e. it divides an
+// hour evenly so rounded values line up with clock time.
+func validRoundTo(n int) bool {
+	return n >= 1 && n <= 60 && 60%n == 0
+}
+
+// projectRoundTo returns the stored-rounding increment for a session of
+// projectID: the project's round_to if set, else ROUND_TO_MINUTES.
+func (s *Server) projectRoundTo(q queryer, projectID *int64) (int, error) {
+	if projectID == nil {
+		return s.roundTo, nil
+	}
+	var roundTo int
+	err := q.QueryRow(
+		`SELECT COALESCE(round_to, $2) FROM projects WHERE id=$1`, *projectID, s.roundTo,
+	).Scan(&roundTo)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s.roundTo, nil
+	}
+	return roundTo, err
+}
+
+// roundUpMinutes rounds mins up to a multiple of inc (inc <= 1 is a no-op).
+func roundUpMinutes(mins, inc int) int {
+	if inc <= 1 {
+		return mins
+	}
+	return (mins + inc - 1) / inc * inc
+}
+
+// measure returns what a session from start to end, minus paused time,
+// stores: whole seconds, whole minutes (floor, derived from the seconds) and
+// the billing minutes rounded up to roundTo. A negative span counts as zero.
+func measure(start, end time.Time, paused time.Duration, roundTo int) (secs, mins, rounded int) {
+	secs = max(0, int((end.Sub(start)-paused)/time.Second))
+	mins = secs / 60
+	return secs, mins, roundUpMinutes(mins, roundTo)
+}
+
+//
+// ─────────────────────────── Report-time rounding ───────────────────────────
+//
+// Invoice data, earnings and the CSV export can re-round on request with
+// ?rounding=none|nearest|up|down&roundTo=N, without touching what is stored.
+// Each session is rounded on its own, from m = floor(duration_seconds / 60)
+// (the same whole minutes as durationMinutes), to a multiple of I = roundTo:
+//
+//	none     m
+//	down     floor(m / I) * I                 (7, I=15 → 0;  22 → 15)
+//	up       ceil(m / I) * I                  (1..15 → 15;   0 → 0)
+//	nearest  floor((2m + I) / (2I)) * I       (halves go up: 7 → 0, 8 → 15)
+//
+// and then summed. roundTo defaults to ROUND_TO_MINUTES; roundTo alone
+// means mode up, like {roundTo} on stop.
+
+// Rounding is a report-time rounding choice; it is echoed in responses.
+type Rounding struct {
+	Mode    string `json:"mode"` // none, nearest, up or down
+	RoundTo int    `json:"roundTo"`
+}
+
+// parseRounding reads ?rounding=&roundTo=; nil when neither is given, so
+// callers keep using the stored rounded_minutes.
+func parseRounding(q url.Values, defaultRoundTo int) (*Rounding, error) {
+	mode, inc := q.Get("rounding"), q.Get("roundTo")
+	if mode == "" && inc == "" {
+		return nil, nil
+	}
+	rd := Rounding{Mode: mode, RoundTo: defaultRoundTo}
+	switch mode {
+	case "":
+		rd.Mode = "up"
+	case "none", "nearest", "up", "down":
+	default:
+		return nil, errors.New("rounding must be none, nearest, up or down")
+	}
+	if inc != "" {
+		n, err := strconv.Atoi(inc)
+		if err != nil || !validRoundTo(n) {
+			return nil, errors.New("roundTo must divide 60 evenly")
+		}
+		rd.RoundTo = n
+	}
+	return &rd, nil
+}
+
+// minutes rounds one session's raw seconds (see the table above).
+func (rd Rounding) minutes(secs int64) int64 {
+	m, i := secs/60, int64(rd.RoundTo)
+	switch rd.Mode {
+	case "down":
+		return m / i * i
+	case "up":
+		return (m + i - 1) / i * i
+	case "nearest":
+		return (2*m + i) / (2 * i) * i
+	}
+	return m
+}
+
+// sql is minutes as a SQL expression over an integer seconds expression,
+// for per-session rounding inside aggregates. Mode and RoundTo are
+// validated, so inlining them is safe.
+func (rd Rounding) sql(secs string) string {
+	m, i := "(("+secs+") / 60)", strconv.Itoa(rd.RoundTo)
+	switch rd.Mode {
+	case "down":
+		return "(" + m + " / " + i + " * " + i + ")"
+	case "up":
+		return "((" + m + " + " + i + " - 1) / " + i + " * " + i + ")"
+	case "nearest":
+		return "((2 * " + m + " + " + i + ") / (2 * " + i + ") * " + i + ")"
+	}
+	return m
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMeasure(t *testing.T) {
+	start := testStart
+	for _, c := range []struct {
+		span, paused            time.Duration
+		roundTo                 int
+		secs, mins, roundedWant int
+	}{
+		{0, 0, 15, 0, 0, 0},
+		{59 * time.Second, 0, 15, 59, 0, 0},
+		{61 * time.Second, 0, 15, 61, 1, 15},
+		{16 * time.Minute, time.Minute, 15, 900, 15, 15},
+		{16 * time.Minute, 0, 15, 960, 16, 30},
+		{-time.Minute, 0, 15, 0, 0, 0},
+		{90 * time.Minute, 0, 1, 5400, 90, 90},
+	} {
+		secs, mins, rounded := measure(start, start.Add(c.span), c.paused, c.roundTo)
+		if secs != c.secs || mins != c.mins || rounded != c.roundedWant {
+			t.Errorf("measure(%v, paused %v, %d) = %d/%d/%d, want %d/%d/%d",
+				c.span, c.paused, c.roundTo, secs, mins, rounded, c.secs, c.mins, c.roundedWant)
+		}
+	}
+}
+
+func TestRoundToPrecedence(t *testing.T) {
+	cfg := testConfig()
+	cfg.RoundToMinutes = 5
+	e := newTestEnvConfig(t, cfg)
+	_, token := e.user("alice@example.com")
+
+	p := e.project(token, map[string]any{"name": "Acme", "roundTo": 30})
+	if p.RoundTo == nil || *p.RoundTo != 30 {
+		t.Fatalf("project roundTo = %v, want 30", p.RoundTo)
+	}
+
+	// 7 minutes worked each time.
+	stop := func(start, stop map[string]any) int {
+		wantStatus(t, e.do("POST", "/api/time/start", token, start), http.StatusCreated)
+		e.clock.advance(7 * time.Minute)
+		rec := e.do("POST", "/api/time/stop", token, stop)
+		wantStatus(t, rec, http.StatusOK)
+		var ss Session
+		decode(t, rec, &ss)
+		return *ss.RoundedMinutes
+	}
+	if got := stop(nil, nil); got != 10 {
+		t.Errorf("no project: rounded %d, want 10 (global 5)", got)
+	}
+	if got := stop(map[string]any{"projectId": p.ID}, nil); got != 30 {
+		t.Errorf("project: rounded %d, want 30 (project 30)", got)
+	}
+	if got := stop(map[string]any{"projectId": p.ID}, map[string]any{"roundTo": 15}); got != 15 {
+		t.Errorf("project + roundTo on stop: rounded %d, want 15 (session 15)", got)
+	}
+
+	// Manual entries follow the project too.
+	start := e.clock.now().Add(-time.Hour)
+	rec := e.do("POST", "/api/time/manual", token, map[string]any{
+		"startTime": start, "endTime": start.Add(7 * time.Minute), "projectId": p.ID,
+	})
+	wantStatus(t, rec, http.StatusCreated)
+	var ss Session
+	decode(t, rec, &ss)
+	if ss.RoundedMinutes == nil || *ss.RoundedMinutes != 30 {
+		t.Errorf("manual: rounded %v, want 30", ss.RoundedMinutes)
+	}
+
+	// roundTo 0 clears the project's value.
+	rec = e.do("PATCH", "/api/projects/"+int64ToStr(p.ID), token, map[string]any{"roundTo": 0})
+	wantStatus(t, rec, http.StatusOK)
+	decode(t, rec, &p)
+	if p.RoundTo != nil {
+		t.Errorf("after clearing, roundTo = %d, want null", *p.RoundTo)
+	}
+	wantStatus(t, e.do("PATCH", "/api/projects/"+int64ToStr(p.ID), token, map[string]any{"roundTo": 7}), http.StatusBadRequest)
+}
@@ -0,0 +1,730 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//
+// ─────────────────────────────── Row scanning ───────────────────────────────
+//
+
+// sessionCols is the column list scanSession expects, in order.
+const sessionCols = `id, user_id, project_id, start_time, end_time, duration_seconds,
+	duration_minutes, rounded_minutes, note, tags, auto_stopped, version, archived_at IS NOT NULL`
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSession reads one row selected with sessionCols.
+func scanSession(row rowScanner) (Session, error) {
+	var ss Session
+	err := row.Scan(&ss.ID, &ss.UserID, &ss.ProjectID, &ss.StartTime, &ss.EndTime,
+		&ss.DurationSeconds, &ss.DurationMinutes, &ss.RoundedMinutes, &ss.Note, pq.Array(&ss.Tags),
+		&ss.AutoStopped, &ss.Version, &ss.Archived)
+	ss.Tags = tagsOrEmpty(ss.Tags)
+	return ss, err
+}
+
+//
+// ───────────────────────────── Notes, tags, project ─────────────────────────
+//
+
+const (
+	maxNoteLen = 2000
+	maxTags    = 20
+	maxTagLen  = 50
+)
+
+var errNoteTooLong = errors.New("note is too long (max 2000 characters)")
+
+// checkMeta normalizes tags and validates note length and project ownership.
+// Input problems come back as *apiError (see writeErr), others are DB errors.
+func (s *Server) checkMeta(uid int64, m *sessionMeta) error {
+	if len(m.Note) > maxNoteLen {
+		return badRequest(codeInvalidRequest, errNoteTooLong.Error())
+	}
+	tags, err := cleanTags(m.Tags)
+	if err != nil {
+		return badRequest(codeInvalidRequest, err.Error())
+	}
+	m.Tags = tags
+
+	if m.ProjectID != nil {
+		ok, err := s.ownsProject(uid, *m.ProjectID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return badRequest(codeProjectNotFound, "project not found")
+		}
+	}
+	return nil
+}
+
+// cleanTags trims, drops empty and duplicate tags, and enforces the limits.
+// The result is never nil so it is stored as '{}' and serialized as [].
+func cleanTags(in []string) ([]string, error) {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, t := range in {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		if len(t) > maxTagLen {
+			return nil, errors.New("tag is too long (max 50 characters)")
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	if len(out) > maxTags {
+		return nil, errors.New("too many tags (max 20)")
+	}
+	return out, nil
+}
+
+// tagsOrEmpty turns a nil slice into [] for JSON and NOT NULL columns.
+func tagsOrEmpty(t []string) []string {
+	if t == nil {
+		return []string{}
+	}
+	return t
+}
+
+//
+// ─────────────────────────────── Current session ────────────────────────────
+//
+
+// CurrentSession is the running timer; ElapsedSeconds excludes paused time.
+type CurrentSession struct {
+	ID             int64     `json:"id"`
+	StartTime      time.Time `json:"startTime"`
+	ElapsedSeconds int64     `json:"elapsedSeconds"`
+	Paused         bool      `json:"paused"`
+	PausedSeconds  int64     `json:"pausedSeconds"`
+}
+
+// GET /api/time/current
+// Returns the open session as {id, startTime, elapsedSeconds, paused,
+// pausedSeconds}, or 204 No Content when nothing is running (lets the app
+// resume a timer on reload).
+func (s *Server) currentSession(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	cur, err := s.loadCurrent(uid, s.clock.now())
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if cur == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, cur)
+}
+
+// loadCurrent returns the user's oldest open session as of now, or nil.
+func (s *Server) loadCurrent(uid int64, now time.Time) (*CurrentSession, error) {
+	var cur CurrentSession
+	err := s.db.QueryRow(`
+		SELECT id, start_time
+		FROM sessions
+		WHERE user_id=$1 AND end_time IS NULL AND deleted_at IS NULL
+		ORDER BY start_time ASC
+		LIMIT 1
+	`, uid).Scan(&cur.ID, &cur.StartTime)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	paused, pausedNow, err := pauseState(s.db, cur.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	cur.ElapsedSeconds = max(0, int64((now.Sub(cur.StartTime)-paused)/time.Second))
+	cur.Paused = pausedNow
+	cur.PausedSeconds = int64(paused / time.Second)
+	return &cur, nil
+}
+
+//
+// ──────────────────────────────── Manual entry ──────────────────────────────
+//
+
+// Body for POST /api/time/manual (RFC3339 timestamps). The offset is
+// required and honored as sent ("+02:00" is not taken as server-local);
+// TIMESTAMPTZ keeps the instant, so the session reads back at the same
+// moment, possibly rendered with a different offset.
+type manualReq struct {
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	sessionMeta
+}
+
+// POST /api/time/manual
+// Logs a completed session after the fact. Rejects endTime <= startTime (400),
+// times in the future (422 time_in_future, see checkNotFuture) and intervals
+// that overlap another session of the user (409).
+func (s *Server) manualSession(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	var req manualReq
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if req.StartTime.IsZero() || req.EndTime.IsZero() {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "startTime and endTime are required")
+		return
+	}
+	if !req.EndTime.After(req.StartTime) {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "endTime must be after startTime")
+		return
+	}
+	if err := checkNotFuture("endTime", req.EndTime, s.clock.now()); err != nil {
+		writeErr(w, err) // endTime > startTime, so this covers both
+		return
+	}
+	s.insertCompleted(w, uid, req.StartTime, req.EndTime, req.sessionMeta)
+}
+
+// maxLogMinutes caps POST /api/time/log; longer blocks go through
+// /api/time/manual with explicit times.
+const maxLogMinutes = 24 * 60
+
+// Body for POST /api/time/log. endTime defaults to now.
+type logReq struct {
+	DurationMinutes int        `json:"durationMinutes"`
+	EndTime         *time.Time `json:"endTime"`
+	sessionMeta
+}
+
+// POST /api/time/log
+// Quick-adds a block that just ended ("I spent 45 minutes on X"): the start
+// is endTime - durationMinutes. durationMinutes must be 1..maxLogMinutes
+// (400); endTime, overlaps and the metadata are checked as for
+// POST /api/time/manual.
+func (s *Server) logBlock(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	var req logReq
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if req.DurationMinutes <= 0 || req.DurationMinutes > maxLogMinutes {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("durationMinutes must be between 1 and %d", maxLogMinutes))
+		return
+	}
+	now := s.clock.now()
+	end := now
+	if req.EndTime != nil {
+		if err := checkNotFuture("endTime", *req.EndTime, now); err != nil {
+			writeErr(w, err)
+			return
+		}
+		end = *req.EndTime
+	}
+	start := end.Add(-time.Duration(req.DurationMinutes) * time.Minute)
+	s.insertCompleted(w, uid, start, end, req.sessionMeta)
+}
+
+// insertCompleted stores a finished [start, end) session for uid after
+// checking meta and overlaps, and answers 201 with it. Callers have
+// already validated the times.
+func (s *Server) insertCompleted(w http.ResponseWriter, uid int64, start, end time.Time, meta sessionMeta) {
+	if err := s.checkMeta(uid, &meta); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	ov, err := s.hasOverlap(uid, start, &end)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if ov != nil {
+		writeOverlap(w, ov)
+		return
+	}
+
+	roundTo, err := s.projectRoundTo(s.db, meta.ProjectID)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	secs, dur, rounded := measure(start, end, 0, roundTo)
+	// Read the row back so the response carries its defaults (version,
+	// autoStopped, archived) and the client can PATCH it straight away.
+	out, err := scanSession(s.db.QueryRow(`
+		INSERT INTO sessions(user_id, project_id, start_time, end_time, duration_seconds, duration_minutes,
+		                     rounded_minutes, note, tags)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) RETURNING `+sessionCols,
+		uid, meta.ProjectID, start, end, secs, dur, rounded, meta.Note, pq.Array(tagsOrEmpty(meta.Tags))))
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", sessionURL(out.ID))
+	writeJSON(w, http.StatusCreated, out)
+}
+
+// checkNotFuture rejects a client-supplied session time more than
+// maxClientSkew past now with 422 time_in_future. Without it a future start
+// turns into a zero-length session on stop (measure clamps at zero).
+func checkNotFuture(field string, t, now time.Time) error {
+	if t.After(now.Add(maxClientSkew)) {
+		return &apiError{http.StatusUnprocessableEntity, codeTimeInFuture, field + " is in the future"}
+	}
+	return nil
+}
+
+// overlap describes the first existing session that collides with a new one.
+type overlap struct {
+	ID   int64
+	Open bool // the collision is the running session
+}
+
+// hasOverlap returns the earliest session of uid intersecting [start, end),
+// or nil. end == nil means open-ended (a session being started now); open
+// sessions likewise count as running forever.
+func (s *Server) hasOverlap(uid int64, start time.Time, end *time.Time) (*overlap, error) {
+	return findOverlap(s.db, uid, start, end)
+}
+
+// findOverlap is hasOverlap on q, so a transaction sees its own inserts.
+func findOverlap(q queryer, uid int64, start time.Time, end *time.Time) (*overlap, error) {
+	var ov overlap
+	err := q.QueryRow(`
+		SELECT id, end_time IS NULL
+		FROM sessions
+		WHERE user_id=$1 AND deleted_at IS NULL
+		  AND start_time < COALESCE($3, 'infinity'::timestamptz)
+		  AND COALESCE(end_time, 'infinity'::timestamptz) > $2
+		ORDER BY start_time ASC
+		LIMIT 1
+	`, uid, start, end).Scan(&ov.ID, &ov.Open)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ov, nil
+}
+
+// writeOverlap answers 409 with the conflicting session id in the details.
+func writeOverlap(w http.ResponseWriter, ov *overlap) {
+	writeErrorDetails(w, http.StatusConflict, codeSessionOverlap,
+		"overlaps session "+int64ToStr(ov.ID), map[string]int64{"conflictingSessionId": ov.ID})
+}
+
+//
+// ─────────────────────────────── Single session ─────────────────────────────
+//
+
+// GET /api/time/sessions/{id}
+// Returns one Session (archived ones included) for a detail view. Another
+// user's session is 404 like a missing one, so ids can't be probed.
+func (s *Server) getSession(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+	id, err := strToInt64(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "bad session id")
+		return
+	}
+
+	ss, err := scanSession(s.db.QueryRow(`
+		SELECT `+sessionCols+`
+		FROM sessions
+		WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL
+	`, id, uid))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, codeSessionNotFound, "session not found")
+		return
+	}
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ss)
+}
+
+//
+// ──────────────────────────────── Session edits ─────────────────────────────
+//
+
+// Body for PATCH /api/time/sessions/{id}; nil fields are left unchanged.
+// Version is required: the version of the session the client is editing.
+type sessionPatch struct {
+	Version   *int       `json:"version"`
+	StartTime *time.Time `json:"startTime"`
+	EndTime   *time.Time `json:"endTime"`
+	ProjectID *int64     `json:"projectId"`
+	Note      *string    `json:"note"`
+	Tags      *[]string  `json:"tags"`
+}
+
+// PATCH /api/time/sessions/{id}
+// Partially updates start/end/projectId/note/tags and recomputes the duration. 404 if the session
+// doesn't exist, 403 if it belongs to another user, 400 if end <= start, 422
+// time_in_future if a new start or end lies in the future.
+//
+// Optimistic locking: every Session carries a version that changes with
+// each write. Send the version you last read along with the fields; if the
+// session changed since (another tab, a stop), the edit is refused with
+// 409 session_version_conflict and details {currentVersion}. Reload the
+// session, reapply the change and retry. A request without version is 400.
+// The response is the updated Session with its new version.
+func (s *Server) editSession(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+	id, err := strToInt64(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "bad session id")
+		return
+	}
+
+	var req sessionPatch
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if req.Version == nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "version is required")
+		return
+	}
+
+	cur, err := scanSession(s.db.QueryRow(`
+		SELECT `+sessionCols+`
+		FROM sessions
+		WHERE id=$1 AND deleted_at IS NULL
+	`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, codeSessionNotFound, "session not found")
+		return
+	}
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if cur.UserID != uid {
+		writeError(w, http.StatusForbidden, codeForbidden, "not your session")
+		return
+	}
+	if cur.Version != *req.Version {
+		writeVersionConflict(w, cur.Version)
+		return
+	}
+
+	now := s.clock.now()
+	if req.StartTime != nil {
+		if err := checkNotFuture("startTime", *req.StartTime, now); err != nil {
+			writeErr(w, err)
+			return
+		}
+		cur.StartTime = *req.StartTime
+	}
+	if req.EndTime != nil {
+		if err := checkNotFuture("endTime", *req.EndTime, now); err != nil {
+			writeErr(w, err)
+			return
+		}
+		cur.EndTime = req.EndTime
+	}
+	if req.ProjectID != nil {
+		ok, err := s.ownsProject(uid, *req.ProjectID)
+		if err != nil {
+			serverError(w, err)
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusBadRequest, codeProjectNotFound, "project not found")
+			return
+		}
+		cur.ProjectID = req.ProjectID
+	}
+	if req.Note != nil {
+		if len(*req.Note) > maxNoteLen {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, errNoteTooLong.Error())
+			return
+		}
+		cur.Note = *req.Note
+	}
+	if req.Tags != nil {
+		tags, err := cleanTags(*req.Tags)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+			return
+		}
+		cur.Tags = tags
+	}
+	if cur.EndTime != nil && !cur.EndTime.After(cur.StartTime) {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "endTime must be after startTime")
+		return
+	}
+
+	// Closing the pauses and the versioned UPDATE commit together, so a
+	// version conflict leaves the pauses as they were.
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	if cur.EndTime != nil {
+		if err := endPauses(tx, id, *cur.EndTime); err != nil {
+			serverError(w, err)
+			return
+		}
+		paused, _, err := pauseState(tx, id, *cur.EndTime)
+		if err != nil {
+			serverError(w, err)
+			return
+		}
+		roundTo, err := s.projectRoundTo(tx, cur.ProjectID)
+		if err != nil {
+			serverError(w, err)
+			return
+		}
+		secs, dur, rounded := measure(cur.StartTime, *cur.EndTime, paused, roundTo)
+		cur.DurationSeconds, cur.DurationMinutes, cur.RoundedMinutes = &secs, &dur, &rounded
+	}
+
+	// The version check is repeated in the UPDATE to catch a write that
+	// landed after the SELECT above.
+	err = tx.QueryRow(`
+		UPDATE sessions
+		SET start_time=$2, end_time=$3, duration_seconds=$4, duration_minutes=$5, rounded_minutes=$6,
+		    project_id=$7, note=$8, tags=$9
+		WHERE id=$1 AND version=$10
+		RETURNING version
+	`, id, cur.StartTime, cur.EndTime, cur.DurationSeconds, cur.DurationMinutes, cur.RoundedMinutes,
+		cur.ProjectID, cur.Note, pq.Array(cur.Tags), cur.Version).Scan(&cur.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		var v int
+		if err := tx.QueryRow(`SELECT version FROM sessions WHERE id=$1`, id).Scan(&v); err != nil {
+			serverError(w, err)
+			return
+		}
+		writeVersionConflict(w, v)
+		return
+	}
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cur)
+}
+
+// writeVersionConflict answers 409 for a PATCH based on a stale version.
+func writeVersionConflict(w http.ResponseWriter, current int) {
+	writeErrorDetails(w, http.StatusConflict, codeVersionConflict,
+		"session was changed since it was loaded", map[string]int{"currentVersion": current})
+}
+
+//
+// ─────────────────────────── Session maintenance API ────────────────────────
+//
+
+// POST /api/time/clear-today?confirm=true
+// Soft-deletes all of today's sessions (user's zone, optional ?tz=) for the
+// current user, stopping the open one first like stopSession (rounded
+// duration, "stopped" stream event). Runs in a transaction; returns
+// {removed}.
+func (s *Server) clearToday(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("confirm") != "true" {
+		writeError(w, http.StatusBadRequest, codeConfirmationMissing, "confirm=true required")
+		return
+	}
+	uid, _ := userIDFromCtx(r)
+
+	loc, err := s.userLocation(r, uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Close any running session so nothing is left dangling.
+	now := s.clock.now()
+	closed, err := s.closeOpenSessions(tx, uid, now)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+
+	day := today(now, loc)
+	res, err := tx.Exec(`
+		UPDATE sessions SET deleted_at=$2
+		WHERE user_id=$1 AND start_time >= $3 AND start_time < $4 AND deleted_at IS NULL
+	`, uid, now, day.From, day.To)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	removed, _ := res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+	for _, ss := range closed {
+		s.streams.publish(uid, streamEvent{"stopped", ss})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"removed": removed})
+}
+
+// POST /api/time/stop-all
+// Recovery tool: closes every open session of the user in one transaction
+// (end_time = now, durations computed as in stopSession) and returns them.
+func (s *Server) stopAll(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	closed, err := s.closeOpenSessions(tx, uid, s.clock.now())
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+	for _, ss := range closed {
+		s.streams.publish(uid, streamEvent{"stopped", ss})
+	}
+	writeJSON(w, http.StatusOK, closed)
+}
+
+// closeOpenSessions locks and stops every open session of uid at now,
+// closing their pauses, and returns them as stored. Durations are computed
+// as in stopSession. The caller commits tx and publishes the events.
+func (s *Server) closeOpenSessions(tx *sql.Tx, uid int64, now time.Time) ([]Session, error) {
+	rows, err := tx.Query(`
+		SELECT `+sessionCols+`
+		FROM sessions
+		WHERE user_id=$1 AND end_time IS NULL AND deleted_at IS NULL
+		ORDER BY start_time ASC, id ASC
+		FOR UPDATE
+	`, uid)
+	if err != nil {
+		return nil, err
+	}
+	closed := []Session{}
+	for rows.Next() {
+		ss, err := scanSession(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		closed = append(closed, ss)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range closed {
+		paused, _, err := pauseState(tx, closed[i].ID, now)
+		if err != nil {
+			return nil, err
+		}
+		if err := endPauses(tx, closed[i].ID, now); err != nil {
+			return nil, err
+		}
+		roundTo, err := s.projectRoundTo(tx, closed[i].ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		secs, dur, rounded := measure(closed[i].StartTime, now, paused, roundTo)
+		closed[i].EndTime = &now
+		closed[i].DurationSeconds, closed[i].DurationMinutes, closed[i].RoundedMinutes = &secs, &dur, &rounded
+
+		if _, err := tx.Exec(
+			`UPDATE sessions SET end_time=$1, duration_seconds=$2, duration_minutes=$3, rounded_minutes=$4 WHERE id=$5`,
+			now, secs, dur, rounded, closed[i].ID,
+		); err != nil {
+			return nil, err
+		}
+	}
+	return closed, nil
+}
+
+// POST /api/time/archive?before=
+// Archives the user's finished sessions that started before `before`
+// (YYYY-MM-DD in the user's zone, or RFC3339). Archived sessions stay in
+// totals, reports and exports but are left out of GET /api/time/sessions
+// unless includeArchived=true. Returns {archived, before}.
+func (s *Server) archiveBefore(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	v := r.URL.Query().Get("before")
+	if v == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "before is required")
+		return
+	}
+	loc, err := s.userLocation(r, uid)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	before, _, err := parseDateOrTime(v, loc)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "before: "+err.Error())
+		return
+	}
+
+	n, err := s.archiveSessions(r.Context(), uid, before, s.clock.now())
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"archived": n, "before": before})
+}
+
+// archiveSessions sets archived_at=now on finished, not yet archived
+// sessions that started before cutoff; uid 0 means every user (the job).
+// Open sessions are never archived.
+func (s *Server) archiveSessions(ctx context.Context, uid int64, cutoff, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE sessions SET archived_at=$3
+		WHERE ($1 = 0 OR user_id=$1) AND start_time < $2
+		  AND end_time IS NOT NULL AND archived_at IS NULL AND deleted_at IS NULL
+	`, uid, cutoff, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
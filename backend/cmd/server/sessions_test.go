@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartStopFetch(t *testing.T) {
+	e := newTestEnv(t)
+	_, token := e.user("alice@example.com")
+
+	rec := e.do("POST", "/api/time/start", token, map[string]any{"note": "focus"})
+	wantStatus(t, rec, http.StatusCreated)
+	var started Session
+	decode(t, rec, &started)
+	if !started.StartTime.Equal(testStart) {
+		t.Errorf("startTime = %v, want %v", started.StartTime, testStart)
+	}
+	if started.EndTime != nil {
+		t.Errorf("new session already has endTime %v", started.EndTime)
+	}
+
+	// A second start while one is open is a conflict under the default limit.
+	wantStatus(t, e.do("POST", "/api/time/start", token, nil), http.StatusConflict)
+
+	e.clock.advance(90*time.Minute + 30*time.Second)
+	rec = e.do("POST", "/api/time/stop", token, nil)
+	wantStatus(t, rec, http.StatusOK)
+
+	rec = e.do("GET", fmt.Sprintf("/api/time/sessions/%d", started.ID), token, nil)
+	wantStatus(t, rec, http.StatusOK)
+	var got Session
+	decode(t, rec, &got)
+	if got.EndTime == nil || !got.EndTime.Equal(testStart.Add(90*time.Minute+30*time.Second)) {
+		t.Fatalf("endTime = %v, want start + 90m30s", got.EndTime)
+	}
+	if got.DurationSeconds == nil || *got.DurationSeconds != 5430 {
+		t.Errorf("durationSeconds = %v, want 5430", got.DurationSeconds)
+	}
+	if got.DurationMinutes == nil || *got.DurationMinutes != 90 {
+		t.Errorf("durationMinutes = %v, want 90", got.DurationMinutes)
+	}
+	if got.Note != "focus" {
+		t.Errorf("note = %q, want focus", got.Note)
+	}
+
+	// Nothing is open any more.
+	wantStatus(t, e.do("POST", "/api/time/stop", token, nil), http.StatusNotFound)
+}
+
+func TestGetSessionOtherUser(t *testing.T) {
+	e := newTestEnv(t)
+	_, alice := e.user("alice@example.com")
+	_, bob := e.user("bob@example.com")
+
+	rec := e.do("POST", "/api/time/start", alice, nil)
+	wantStatus(t, rec, http.StatusCreated)
+	var ss Session
+	decode(t, rec, &ss)
+
+	// Bob can't tell Alice's session from one that doesn't exist.
+	wantStatus(t, e.do("GET", fmt.Sprintf("/api/time/sessions/%d", ss.ID), bob, nil), http.StatusNotFound)
+}
+
+func TestManualSessionKeepsOffset(t *testing.T) {
+	e := newTestEnv(t)
+	_, token := e.user("alice@example.com")
+
+	// 08:15-09:45 in UTC+2 is 06:15-07:45 UTC, before the fake clock's 09:00.
+	body := `{"startTime":"2024-03-04T08:15:00+02:00","endTime":"2024-03-04T09:45:30+02:00"}`
+	rec := e.do("POST", "/api/time/manual", token, body)
+	wantStatus(t, rec, http.StatusCreated)
+	var created Session
+	decode(t, rec, &created)
+
+	rec = e.do("GET", fmt.Sprintf("/api/time/sessions/%d", created.ID), token, nil)
+	wantStatus(t, rec, http.StatusOK)
+	var got Session
+	decode(t, rec, &got)
+
+	wantStart := time.Date(2024, 3, 4, 6, 15, 0, 0, time.UTC)
+	wantEnd := time.Date(2024, 3, 4, 7, 45, 30, 0, time.UTC)
+	if !got.StartTime.Equal(wantStart) {
+		t.Errorf("startTime = %v, want %v", got.StartTime, wantStart)
+	}
+	if got.EndTime == nil || !got.EndTime.Equal(wantEnd) {
+		t.Errorf("endTime = %v, want %v", got.EndTime, wantEnd)
+	}
+	if got.DurationSeconds == nil || *got.DurationSeconds != 90*60+30 {
+		t.Errorf("durationSeconds = %v, want 5430", got.DurationSeconds)
+	}
+	if got.DurationMinutes == nil || *got.DurationMinutes != 90 {
+		t.Errorf("durationMinutes = %v, want 90", got.DurationMinutes)
+	}
+
+	// The stored instant is the same one the database reports in UTC.
+	var stored time.Time
+	if err := e.s.db.QueryRow(`SELECT start_time FROM sessions WHERE id=$1`, created.ID).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Equal(wantStart) {
+		t.Errorf("stored start_time = %v, want %v", stored, wantStart)
+	}
+}
+
+// Manually created sessions come back with the row's version, so a
+// follow-up PATCH using it isn't refused as stale.
+func TestCreatedSessionPatchableWithReturnedVersion(t *testing.T) {
+	e := newTestEnv(t)
+	_, token := e.user("alice@example.com")
+
+	for _, c := range []struct {
+		path string
+		body map[string]any
+	}{
+		{"/api/time/manual", map[string]any{
+			"startTime": testStart.Add(-3 * time.Hour), "endTime": testStart.Add(-2 * time.Hour),
+		}},
+		{"/api/time/log", map[string]any{"durationMinutes": 45}},
+	} {
+		rec := e.do("POST", c.path, token, c.body)
+		wantStatus(t, rec, http.StatusCreated)
+		var created Session
+		decode(t, rec, &created)
+		if created.Version < 1 {
+			t.Errorf("%s: version = %d, want the stored version", c.path, created.Version)
+		}
+
+		rec = e.do("PATCH", fmt.Sprintf("/api/time/sessions/%d", created.ID), token,
+			map[string]any{"version": created.Version, "note": "edited"})
+		wantStatus(t, rec, http.StatusOK)
+		var got Session
+		decode(t, rec, &got)
+		if got.Note != "edited" || got.Version <= created.Version {
+			t.Errorf("%s: after PATCH note=%q version=%d, want edited and a version past %d",
+				c.path, got.Note, got.Version, created.Version)
+		}
+	}
+}
+
+func TestStopTimeKeepsOffset(t *testing.T) {
+	e := newTestEnv(t)
+	_, token := e.user("alice@example.com")
+
+	wantStatus(t, e.do("POST", "/api/time/start", token, nil), http.StatusCreated)
+	e.clock.advance(2 * time.Hour)
+
+	// Stopped offline at 10:30 UTC, reported as 12:30+02:00.
+	rec := e.do("POST", "/api/time/stop", token, `{"stopTime":"2024-03-04T12:30:00+02:00"}`)
+	wantStatus(t, rec, http.StatusOK)
+	var got Session
+	decode(t, rec, &got)
+	if want := testStart.Add(90 * time.Minute); got.EndTime == nil || !got.EndTime.Equal(want) {
+		t.Errorf("endTime = %v, want %v", got.EndTime, want)
+	}
+	if got.DurationMinutes == nil || *got.DurationMinutes != 90 {
+		t.Errorf("durationMinutes = %v, want 90", got.DurationMinutes)
+	}
+}
+
+func TestConcurrentStopsCloseOnce(t *testing.T) {
+	e := newTestEnv(t)
+	_, token := e.user("alice@example.com")
+	wantStatus(t, e.do("POST", "/api/time/start", token, nil), http.StatusCreated)
+	e.clock.advance(time.Hour)
+
+	const n = 8
+	codes := make(chan int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- e.do("POST", "/api/time/stop", token, nil).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	ok := 0
+	for c := range codes {
+		switch c {
+		case http.StatusOK:
+			ok++
+		case http.StatusNotFound, http.StatusConflict:
+		default:
+			t.Errorf("unexpected status %d", c)
+		}
+	}
+	if ok != 1 {
+		t.Errorf("%d stops succeeded, want exactly 1", ok)
+	}
+
+	var closed, pauses int
+	if err := e.s.db.QueryRow(`SELECT COUNT(*) FROM sessions WHERE end_time IS NOT NULL AND duration_minutes=60`).Scan(&closed); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.s.db.QueryRow(`SELECT COUNT(*) FROM session_pauses WHERE resumed_at IS NULL`).Scan(&pauses); err != nil {
+		t.Fatal(err)
+	}
+	if closed != 1 || pauses != 0 {
+		t.Errorf("closed sessions = %d, dangling pauses = %d; want 1 and 0", closed, pauses)
+	}
+}
+
+func TestEditVersionConflictKeepsPauses(t *testing.T) {
+	e := newTestEnv(t)
+	_, token := e.user("alice@example.com")
+
+	rec := e.do("POST", "/api/time/start", token, nil)
+	wantStatus(t, rec, http.StatusCreated)
+	var ss Session
+	decode(t, rec, &ss)
+	e.clock.advance(30 * time.Minute)
+	wantStatus(t, e.do("POST", "/api/time/pause", token, nil), http.StatusOK)
+	e.clock.advance(30 * time.Minute)
+
+	rec = e.do("GET", fmt.Sprintf("/api/time/sessions/%d", ss.ID), token, nil)
+	wantStatus(t, rec, http.StatusOK)
+	decode(t, rec, &ss)
+
+	openPauses := func() int {
+		var n int
+		if err := e.s.db.QueryRow(`SELECT COUNT(*) FROM session_pauses WHERE session_id=$1 AND resumed_at IS NULL`, ss.ID).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	// A stale version is refused and must not close the pause.
+	end := testStart.Add(45 * time.Minute)
+	rec = e.do("PATCH", fmt.Sprintf("/api/time/sessions/%d", ss.ID), token,
+		map[string]any{"version": ss.Version - 1, "endTime": end})
+	wantStatus(t, rec, http.StatusConflict)
+	if n := openPauses(); n != 1 {
+		t.Fatalf("open pauses after conflict = %d, want 1", n)
+	}
+
+	rec = e.do("PATCH", fmt.Sprintf("/api/time/sessions/%d", ss.ID), token,
+		map[string]any{"version": ss.Version, "endTime": end})
+	wantStatus(t, rec, http.StatusOK)
+	var got Session
+	decode(t, rec, &got)
+	if n := openPauses(); n != 0 {
+		t.Errorf("open pauses after edit = %d, want 0", n)
+	}
+	// 45 minutes wall clock, of which 15 were paused.
+	if got.DurationMinutes == nil || *got.DurationMinutes != 30 {
+		t.Errorf("durationMinutes = %v, want 30", got.DurationMinutes)
+	}
+}
+
+func TestClearTodayStopsOpenSession(t *testing.T) {
+	cfg := testConfig()
+	cfg.RoundToMinutes = 15
+	e := newTestEnvConfig(t, cfg)
+	uid, token := e.user("alice@example.com")
+
+	rec := e.do("POST", "/api/time/start", token, nil)
+	wantStatus(t, rec, http.StatusCreated)
+	var ss Session
+	decode(t, rec, &ss)
+	e.clock.advance(25*time.Minute + 10*time.Second)
+
+	events, unsubscribe := e.s.streams.subscribe(uid)
+	defer unsubscribe()
+
+	rec = e.do("POST", "/api/time/clear-today?confirm=true", token, nil)
+	wantStatus(t, rec, http.StatusOK)
+
+	var secs, mins, rounded int
+	if err := e.s.db.QueryRow(
+		`SELECT duration_seconds, duration_minutes, rounded_minutes FROM sessions WHERE id=$1`, ss.ID,
+	).Scan(&secs, &mins, &rounded); err != nil {
+		t.Fatalf("closed session: %v", err)
+	}
+	if secs != 25*60+10 || mins != 25 || rounded != 30 {
+		t.Errorf("durations = %ds / %dm / %dm rounded, want 1510s / 25m / 30m", secs, mins, rounded)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Name != "stopped" {
+			t.Errorf("stream event %q, want stopped", ev.Name)
+		}
+	default:
+		t.Error("no stream event for the stopped session")
+	}
+}
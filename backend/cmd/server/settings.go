@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+//
+// ─────────────────────────────── Client settings ────────────────────────────
+//
+
+// Settings is the effective server policy a client should mirror in its UI
+// and validation, so limits aren't duplicated as magic numbers on both ends.
+//
+// MinSessionMinutes, MaxSessionsPerDay and GraceWindowSeconds are always
+// null: the server has no such limits yet. They are in the payload so
+// clients can treat null as "unlimited" now and pick up real values later
+// without a schema change.
+type Settings struct {
+	MinPasswordLength  int             `json:"minPasswordLength"`
+	MaxPasswordBytes   int             `json:"maxPasswordBytes"` // UTF-8 bytes, not characters
+	PasswordPolicy     PasswordPolicy  `json:"passwordPolicy"`
+	MaxOpenSessions    int             `json:"maxOpenSessions"`
+	OpenSessionScope   string          `json:"openSessionScope"` // "user" or "project"
+	MaxSessionHours    int             `json:"maxSessionHours"`  // open longer than this = auto-stopped; 0 = never
+	RoundToMinutes     int             `json:"roundToMinutes"`
+	AuthRateLimit      AuthRateLimit   `json:"authRateLimit"`
+	MinSessionMinutes  *int            `json:"minSessionMinutes"`
+	MaxSessionsPerDay  *int            `json:"maxSessionsPerDay"`
+	GraceWindowSeconds *int            `json:"graceWindowSeconds"`
+	Features           map[string]bool `json:"features"`
+}
+
+// AuthRateLimit is the throttle on login, register and the password-reset
+// endpoints (see ratelimit.go); over it they answer 429 with Retry-After.
+type AuthRateLimit struct {
+	PerIPPerMinute    int `json:"perIpPerMinute"`
+	PerEmailPerMinute int `json:"perEmailPerMinute"`
+}
+
+// effectiveSettings collects the current policy from the server config.
+func (s *Server) effectiveSettings() Settings {
+	return Settings{
+		MinPasswordLength: s.passwordPolicy.MinLength,
+		MaxPasswordBytes:  maxPasswordBytes,
+		PasswordPolicy:    s.passwordPolicy,
+		MaxOpenSessions:   s.maxOpen,
+		OpenSessionScope:  openScope(s.openPerProject),
+		MaxSessionHours:   int(s.maxSession / time.Hour),
+		RoundToMinutes:    s.roundTo,
+		AuthRateLimit: AuthRateLimit{
+			PerIPPerMinute:    s.authLimits.byIP.perMinute,
+			PerEmailPerMinute: s.authLimits.byEmail.perMinute,
+		},
+		Features: map[string]bool{
+			"concurrentSessions": s.maxOpen > 1 || s.openPerProject,
+			"twoFactorAuth":      s.totpKey != nil,
+		},
+	}
+}
+
+// openScope names the OPEN_SESSION_SCOPE in effect.
+func openScope(perProject bool) string {
+	if perProject {
+		return "project"
+	}
+	return "user"
+}
+
+// GET /api/settings
+// Returns the effective limits and feature flags. The values only change on
+// redeploy, so clients may cache the response briefly.
+func (s *Server) settings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	writeJSON(w, http.StatusOK, s.effectiveSettings())
+}
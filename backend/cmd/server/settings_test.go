@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSettingsFromConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxSessionHours = 12
+	cfg.AuthRateIPPerMinute = 20
+	cfg.AuthRateEmailPerMinute = 5
+	s := newServer(cfg, nil)
+
+	got := s.effectiveSettings()
+	if got.MaxSessionHours != 12 {
+		t.Errorf("maxSessionHours = %d, want 12", got.MaxSessionHours)
+	}
+	if got.AuthRateLimit != (AuthRateLimit{PerIPPerMinute: 20, PerEmailPerMinute: 5}) {
+		t.Errorf("authRateLimit = %+v, want 20/5", got.AuthRateLimit)
+	}
+	if s.maxSession != 12*time.Hour {
+		t.Errorf("maxSession = %v, want 12h", s.maxSession)
+	}
+
+	// Limits the server doesn't have are sent as explicit nulls.
+	var raw map[string]json.RawMessage
+	b, _ := json.Marshal(got)
+	if err := json.Unmarshal(b, &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"minSessionMinutes", "maxSessionsPerDay", "graceWindowSeconds"} {
+		if v, ok := raw[k]; !ok || string(v) != "null" {
+			t.Errorf("%s = %s, want null", k, v)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//
+// ───────────────────────────── Live updates (SSE) ───────────────────────────
+//
+
+// streamEvent is one Server-Sent Event: "event: <Name>" plus Data as JSON.
+type streamEvent struct {
+	Name string
+	Data any
+}
+
+// streamBuffer is how many events may queue for a slow client before new
+// ones are dropped; the next tick brings it back in sync anyway.
+const streamBuffer = 16
+
+// streamHub fans session changes out to each user's open streams. It is
+// per process: with several replicas, a change made through another one
+// only shows up with the next tick, which re-reads the database.
+type streamHub struct {
+	mu   sync.Mutex
+	subs map[int64]map[chan streamEvent]struct{}
+}
+
+func newStreamHub() *streamHub {
+	return &streamHub{subs: map[int64]map[chan streamEvent]struct{}{}}
+}
+
+// subscribe registers a stream of uid; call the returned func when it ends.
+func (h *streamHub) subscribe(uid int64) (<-chan streamEvent, func()) {
+	ch := make(chan streamEvent, streamBuffer)
+	h.mu.Lock()
+	if h.subs[uid] == nil {
+		h.subs[uid] = map[chan streamEvent]struct{}{}
+	}
+	h.subs[uid][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs[uid], ch)
+		if len(h.subs[uid]) == 0 {
+			delete(h.subs, uid)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// publish sends ev to every stream of uid without blocking the caller.
+func (h *streamHub) publish(uid int64, ev streamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[uid] {
+		select {
+		case ch <- ev:
+		default: // client is behind; it resyncs on the next tick
+		}
+	}
+}
+
+// GET /api/time/stream
+// Server-Sent Events for a live timer without polling:
+//
+//	event: current   on connect; the open session as in /api/time/current, or null
+//	event: started   a session was started (the new Session)
+//	event: stopped   a session was stopped (the finished Session; once per session for stop-all, clear-today and auto-stop)
+//	event: tick      every STREAM_TICK_INTERVAL while a session runs (as "current")
+//
+// Idle ticks send a ": keepalive" comment instead. Browsers' EventSource
+// cannot send the Authorization header; use a fetch-based SSE client.
+func (s *Server) streamSessions(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	// WRITE_TIMEOUT bounds ordinary responses; a stream lives until the
+	// client goes away.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		serverError(w, err)
+		return
+	}
+
+	events, unsubscribe := s.streams.subscribe(uid)
+	defer unsubscribe()
+
+	cur, err := s.loadCurrent(uid, s.clock.now())
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-store")
+	h.Set("X-Accel-Buffering", "no") // nginx: don't buffer the stream
+	w.WriteHeader(http.StatusOK)
+	if writeEvent(w, rc, streamEvent{"current", cur}) != nil {
+		return
+	}
+
+	t := time.NewTicker(s.streamTick)
+	defer t.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-events:
+			err = writeEvent(w, rc, ev)
+		case <-t.C:
+			cur, lerr := s.loadCurrent(uid, s.clock.now())
+			switch {
+			case lerr != nil:
+				// Keep the stream; the next tick retries.
+				slog.WarnContext(r.Context(), "stream tick failed", "user_id", uid, "err", lerr)
+				continue
+			case cur == nil:
+				if _, err = fmt.Fprint(w, ": keepalive\n\n"); err == nil {
+					err = rc.Flush()
+				}
+			default:
+				err = writeEvent(w, rc, streamEvent{"tick", cur})
+			}
+		}
+		if err != nil {
+			return // client gone
+		}
+	}
+}
+
+// writeEvent writes one SSE message and flushes it to the client.
+func writeEvent(w http.ResponseWriter, rc *http.ResponseController, ev streamEvent) error {
+	data, err := json.Marshal(ev.Data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Name, data); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
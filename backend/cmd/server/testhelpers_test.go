@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"golang.org/x/crypto/bcrypt"
+)
+
+//
+// ─────────────────────────────── Test harness ───────────────────────────────
+//
+// Handler tests run against a real Postgres: DATABASE_URL if set, otherwise
+// a postgres:16 container started once per test binary. Without either (no
+// Docker on the machine) DB tests skip. Every test gets its own freshly
+// created and migrated database, so tests never see each other's rows.
+
+var (
+	pgOnce      sync.Once
+	pgURL       string
+	pgErr       error
+	pgContainer testcontainers.Container
+	dbSeq       atomic.Int64
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if pgContainer != nil {
+		_ = testcontainers.TerminateContainer(pgContainer)
+	}
+	os.Exit(code)
+}
+
+// postgresURL returns an admin connection string, starting the container on
+// first use, or skips t when no Postgres is available.
+func postgresURL(t *testing.T) string {
+	t.Helper()
+	pgOnce.Do(func() {
+		if u := os.Getenv("DATABASE_URL"); u != "" {
+			pgURL = u
+			return
+		}
+		// testcontainers panics rather than erroring when it finds no
+		// Docker host at all.
+		defer func() {
+			if r := recover(); r != nil {
+				pgErr = fmt.Errorf("%v", r)
+			}
+		}()
+		ctx := context.Background()
+		provider, err := testcontainers.ProviderDocker.GetProvider()
+		if err != nil {
+			pgErr = err
+			return
+		}
+		if err := provider.Health(ctx); err != nil {
+			pgErr = err
+			return
+		}
+		ctr, err := tcpostgres.Run(ctx, "postgres:16-alpine",
+			tcpostgres.WithDatabase("timetrac"),
+			tcpostgres.WithUsername("app"),
+			tcpostgres.WithPassword("apppass"),
+			testcontainers.WithWaitStrategy(
+				wait.ForLog("database system is ready to accept connections").
+					WithOccurrence(2).
+					WithStartupTimeout(60*time.Second)),
+		)
+		if err != nil {
+			pgErr = err
+			return
+		}
+		pgContainer = ctr
+		pgURL, pgErr = ctr.ConnectionString(ctx, "sslmode=disable")
+	})
+	if pgURL == "" {
+		t.Skipf("no Postgres for DB tests (set DATABASE_URL or run Docker): %v", pgErr)
+	}
+	return pgURL
+}
+
+// testDB creates an empty database for t and drops it when t ends.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	admin, err := sql.Open("postgres", postgresURL(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	name := fmt.Sprintf("timetrac_test_%d_%d", os.Getpid(), dbSeq.Add(1))
+	if _, err := admin.Exec(`CREATE DATABASE ` + name); err != nil {
+		t.Fatalf("create test database: %v", err)
+	}
+
+	u, err := url.Parse(pgURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Path = "/" + name
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if _, err := admin.Exec(`DROP DATABASE IF EXISTS ` + name + ` WITH (FORCE)`); err != nil {
+			t.Logf("drop test database: %v", err)
+		}
+	})
+	return db
+}
+
+// migratedDB is testDB with every migration applied.
+func migratedDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db := testDB(t)
+	if err := migrate(context.Background(), db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// fakeClock is a Clock that only moves when a test advances it.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func newFakeClock(t time.Time) *fakeClock { return &fakeClock{t: t} }
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.t = c.t.Add(d)
+	c.mu.Unlock()
+}
+
+// testStart is where every fake clock starts: a Monday morning, in the past
+// so rows stamped by the database clock (created_at) never predate it.
+var testStart = time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+
+// testConfig is a valid Config for tests: cheap bcrypt, loose rate limits,
+// no mail server, 1-minute rounding.
+func testConfig() Config {
+	return Config{
+		CORSOrigins:              []string{"*"},
+		JWTSecret:                "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx",
+		MaxBodyBytes:             1 << 20,
+		TokenTTL:                 time.Hour,
+		RefreshTTL:               24 * time.Hour,
+		JWTIssuer:                "timetrac-api",
+		JWTAudience:              "timetrac-app",
+		IdempotencyTTL:           time.Hour,
+		RoundToMinutes:           1,
+		MaxOpenSessions:          1,
+		OpenSessionScope:         "user",
+		StreamTickInterval:       time.Second,
+		AuthRateIPPerMinute:      1000,
+		AuthRateEmailPerMinute:   1000,
+		LockoutThreshold:         5,
+		LockoutDuration:          15 * time.Minute,
+		RequireEmailVerification: true,
+		PublicURL:                "http://localhost:8100",
+		MailFrom:                 "timetrac@localhost",
+		PasswordPolicy:           PasswordPolicy{MinLength: 8},
+		BcryptCost:               bcrypt.MinCost,
+	}
+}
+
+// testEnv is a Server on a migrated database with a fake clock, served the
+// way main serves it minus the logging/metrics layers.
+type testEnv struct {
+	t       *testing.T
+	s       *Server
+	clock   *fakeClock
+	handler http.Handler
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	return newTestEnvConfig(t, testConfig())
+}
+
+func newTestEnvConfig(t *testing.T, cfg Config) *testEnv {
+	t.Helper()
+	s := newServer(cfg, migratedDB(t))
+	clock := newFakeClock(testStart)
+	s.clock = clock
+	mux := s.routes()
+	return &testEnv{
+		t:       t,
+		s:       s,
+		clock:   clock,
+		handler: limitBody(cfg.MaxBodyBytes, recoverer(s.muxErrors(mux))),
+	}
+}
+
+// user inserts a verified user and returns its id and an access token.
+func (e *testEnv) user(email string) (int64, string) {
+	e.t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	var uid int64
+	if err := e.s.db.QueryRow(
+		`INSERT INTO users(email, password_hash, email_verified) VALUES ($1,$2,true) RETURNING id`,
+		email, string(hash),
+	).Scan(&uid); err != nil {
+		e.t.Fatalf("insert user: %v", err)
+	}
+	token, _, err := e.s.issueAccessToken(e.s.db, uid)
+	if err != nil {
+		e.t.Fatalf("issue token: %v", err)
+	}
+	return uid, token
+}
+
+// project creates a project for token's user from the POST /api/projects
+// body and returns it.
+func (e *testEnv) project(token string, body map[string]any) Project {
+	e.t.Helper()
+	rec := e.do("POST", "/api/projects", token, body)
+	wantStatus(e.t, rec, http.StatusCreated)
+	var p Project
+	decode(e.t, rec, &p)
+	return p
+}
+
+// do serves one request; body is JSON-encoded unless it is nil or a string.
+func (e *testEnv) do(method, target, token string, body any) *httptest.ResponseRecorder {
+	e.t.Helper()
+	var buf bytes.Buffer
+	switch b := body.(type) {
+	case nil:
+	case string:
+		buf.WriteString(b)
+	default:
+		if err := json.NewEncoder(&buf).Encode(b); err != nil {
+			e.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	e.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode unmarshals a response body into v, failing t on bad JSON.
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+}
+
+// wantStatus fails t unless rec has the given status.
+func wantStatus(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, status, rec.Body.String())
+	}
+}
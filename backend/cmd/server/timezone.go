@@ -0,0 +1,59 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+	_ "time/tzdata" // don't depend on the container image shipping zoneinfo
+)
+
+//
+// ──────────────────────────────── Time zones ────────────────────────────────
+//
+// "Today" (and plain YYYY-MM-DD dates) are whole days in the user's zone,
+// never the server's. The zone is resolved per request:
+//
+//	?tz=<IANA name>  >  the user's saved preference  >  UTC
+
+// loadTimezone validates an IANA zone name. "Local" is refused because it
+// would silently mean the server's clock again.
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, errors.New("timezone must be an IANA name like Europe/Vienna")
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.New("unknown timezone " + name)
+	}
+	return loc, nil
+}
+
+// userLocation resolves the zone for this request. An invalid ?tz is an
+// *apiError (400); other errors come from the database.
+func (s *Server) userLocation(r *http.Request, uid int64) (*time.Location, error) {
+	if name := r.URL.Query().Get("tz"); name != "" {
+		loc, err := loadTimezone(name)
+		if err != nil {
+			return nil, badRequest(codeInvalidRequest, err.Error())
+		}
+		return loc, nil
+	}
+
+	var name string
+	err := s.db.QueryRow(`SELECT timezone FROM users WHERE id=$1`, uid).Scan(&name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if loc, err := loadTimezone(name); err == nil {
+		return loc, nil
+	}
+	return time.UTC, nil
+}
+
+// today is the user's current calendar day as a half-open range.
+func today(now time.Time, loc *time.Location) dateRange {
+	y, m, d := now.In(loc).Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	return dateRange{From: from, To: from.AddDate(0, 0, 1)}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+//
+// ──────────────────────────────── Token issuing ─────────────────────────────
+//
+
+// Values of claims.Type. Access tokens leave it empty so tokens issued before
+// refresh support keep working.
+const (
+	tokenAccess  = ""
+	tokenRefresh = "refresh"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// parseClaims verifies a JWT's signature, expiry, issuer and audience and
+// returns its claims. Callers still have to check the jti server-side.
+// Only HS256 is accepted, so "alg": "none" or an RS/HS mix-up can't slip a
+// forged token past the key check.
+// Tokens signed before JWT_ISSUER/JWT_AUDIENCE existed carry neither and are
+// rejected, so those users log in once more.
+func (s *Server) parseClaims(tokenStr string) (*claims, error) {
+	tkn, err := jwt.ParseWithClaims(tokenStr, &claims{}, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return s.jwtSecret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(s.jwtIssuer),
+		jwt.WithAudience(s.jwtAudience),
+		jwt.WithTimeFunc(s.clock.now),
+	)
+	if err != nil {
+		return nil, err
+	}
+	cl, ok := tkn.Claims.(*claims)
+	if !ok || !tkn.Valid {
+		return nil, errors.New("invalid claims")
+	}
+	return cl, nil
+}
+
+// issueAccessToken signs a short-lived JWT and records its jti in auth_tokens.
+func (s *Server) issueAccessToken(q execer, uid int64) (string, time.Time, error) {
+	return s.issueToken(q, `INSERT INTO auth_tokens(jti, user_id, expires_at) VALUES ($1,$2,$3)`,
+		uid, tokenAccess, s.tokenTTL)
+}
+
+// issueRefreshToken signs a long-lived JWT and records its jti in refresh_tokens.
+func (s *Server) issueRefreshToken(q execer, uid int64) (string, time.Time, error) {
+	return s.issueToken(q, `INSERT INTO refresh_tokens(jti, user_id, expires_at) VALUES ($1,$2,$3)`,
+		uid, tokenRefresh, s.refreshTTL)
+}
+
+func (s *Server) issueToken(q execer, insert string, uid int64, typ string, ttl time.Duration) (string, time.Time, error) {
+	jti := uuid.New().String()
+	now := s.clock.now()
+	exp := now.Add(ttl)
+
+	cl := &claims{
+		UserID: uid,
+		JTI:    jti,
+		Type:   typ,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.jwtIssuer,
+			Audience:  jwt.ClaimStrings{s.jwtAudience},
+			ExpiresAt: jwt.NewNumericDate(exp),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, cl).SignedString(s.jwtSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if _, err := q.Exec(insert, jti, uid, exp); err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, exp, nil
+}
+
+//
+// ─────────────────────────────── Refresh handler ────────────────────────────
+//
+
+type refreshReq struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// POST /auth/refresh
+// Accepts {refreshToken} and returns a fresh access token plus a rotated
+// refresh token. The presented refresh jti is revoked in the same
+// transaction, so replaying it fails.
+func (s *Server) refresh(w http.ResponseWriter, r *http.Request) {
+
+	var req refreshReq
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+	cl, err := s.parseClaims(req.RefreshToken)
+	if err != nil || cl.Type != tokenRefresh {
+		writeError(w, http.StatusUnauthorized, codeInvalidToken, "invalid refresh token")
+		return
+	}
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Single conditional UPDATE = atomic "use once"; a concurrent replay
+	// blocks on the row lock and then sees revoked_at set.
+	res, err := tx.Exec(`
+		UPDATE refresh_tokens SET revoked_at=$3
+		WHERE jti=$1 AND user_id=$2 AND revoked_at IS NULL AND expires_at > $3
+	`, cl.JTI, cl.UserID, s.clock.now())
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		writeError(w, http.StatusUnauthorized, codeInvalidToken, "refresh token revoked/expired")
+		return
+	}
+
+	var suspendedAt sql.NullTime
+	if err := tx.QueryRow(
+		`SELECT suspended_at FROM users WHERE id=$1`, cl.UserID,
+	).Scan(&suspendedAt); err != nil {
+		serverError(w, err)
+		return
+	}
+	if suspendedAt.Valid {
+		writeError(w, http.StatusForbidden, codeAccountSuspended, "account suspended")
+		return
+	}
+
+	token, exp, err := s.issueAccessToken(tx, cl.UserID)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	refresh, refreshExp, err := s.issueRefreshToken(tx, cl.UserID)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"token":        token,
+		"exp":          exp,
+		"refreshToken": refresh,
+		"refreshExp":   refreshExp,
+	})
+}
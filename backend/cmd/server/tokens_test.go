@@ -0,0 +1,113 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// nopExec stands in for the auth_tokens insert in tests that only sign and
+// parse tokens.
+type nopExec struct{}
+
+func (nopExec) Exec(string, ...any) (sql.Result, error) { return nil, nil }
+
+func tokenServer(t *testing.T) (*Server, *fakeClock) {
+	t.Helper()
+	s := newServer(testConfig(), nil)
+	clock := newFakeClock(testStart)
+	s.clock = clock
+	return s, clock
+}
+
+func TestParseClaimsValid(t *testing.T) {
+	s, _ := tokenServer(t)
+	tok, _, err := s.issueAccessToken(nopExec{}, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl, err := s.parseClaims(tok)
+	if err != nil {
+		t.Fatalf("parseClaims: %v", err)
+	}
+	if cl.UserID != 42 || cl.Type != tokenAccess || cl.JTI == "" {
+		t.Errorf("claims = %+v", cl)
+	}
+}
+
+func TestParseClaimsRejectsAlgNone(t *testing.T) {
+	s, _ := tokenServer(t)
+	cl := &claims{
+		UserID: 1,
+		JTI:    "6f1c1d4e-0000-4000-8000-000000000000",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.jwtIssuer,
+			Audience:  jwt.ClaimStrings{s.jwtAudience},
+			ExpiresAt: jwt.NewNumericDate(testStart.Add(time.Hour)),
+		},
+	}
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, cl).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.parseClaims(none); err == nil {
+		t.Error(`alg "none" token accepted`)
+	}
+
+	// Same payload, header hand-edited to "none" on a real token.
+	signed, _, err := s.issueAccessToken(nopExec{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(signed, ".")
+	parts[0] = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	if _, err := s.parseClaims(parts[0] + "." + parts[1] + "."); err == nil {
+		t.Error("re-headed alg none token accepted")
+	}
+
+	// Another HMAC size with the right secret is refused too.
+	hs512, err := jwt.NewWithClaims(jwt.SigningMethodHS512, cl).SignedString(s.jwtSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.parseClaims(hs512); err == nil {
+		t.Error("HS512 token accepted")
+	}
+}
+
+func TestParseClaimsExpiryFollowsClock(t *testing.T) {
+	s, clock := tokenServer(t)
+	tok, exp, err := s.issueAccessToken(nopExec{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exp.Equal(testStart.Add(s.tokenTTL)) {
+		t.Errorf("exp = %v, want issue time + TTL", exp)
+	}
+	clock.advance(s.tokenTTL - time.Second)
+	if _, err := s.parseClaims(tok); err != nil {
+		t.Errorf("token refused before expiry: %v", err)
+	}
+	clock.advance(2 * time.Second)
+	if _, err := s.parseClaims(tok); err == nil {
+		t.Error("expired token accepted")
+	}
+}
+
+func TestParseClaimsIssuerAudience(t *testing.T) {
+	s, _ := tokenServer(t)
+	other := newServer(testConfig(), nil)
+	other.clock = s.clock
+	other.jwtAudience = "someone-else"
+	tok, _, err := other.issueAccessToken(nopExec{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.parseClaims(tok); err == nil {
+		t.Error("token for another audience accepted")
+	}
+}
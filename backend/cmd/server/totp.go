@@ -0,0 +1,311 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+//
+// ─────────────────────────── Two-factor auth (TOTP) ─────────────────────────
+//
+
+// Two-factor auth is opt-in per user: setup stores a sealed secret, enable
+// proves the authenticator app works and switches it on, and from then on
+// login needs {code} (a TOTP code or a recovery code).
+
+const (
+	totpIssuer        = "TimeTrac" // shown by authenticator apps
+	recoveryCodeCount = 10
+)
+
+type totpCodeReq struct {
+	Code string `json:"code"`
+}
+
+type totpDisableReq struct {
+	Password string `json:"password"`
+}
+
+// sealSecret encrypts a TOTP secret with AES-256-GCM; the nonce is prepended.
+func sealSecret(key []byte, secret string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+// openSecret reverses sealSecret.
+func openSecret(key []byte, sealed string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	b, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(b) < gcm.NonceSize() {
+		return "", errors.New("sealed totp secret too short")
+	}
+	plain, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	return string(plain), err
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newRecoveryCodes returns n codes like "k3mfq-7xw2a" and their hashes.
+func newRecoveryCodes(n int) (codes, hashes []string, err error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for range n {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		c := strings.ToLower(enc.EncodeToString(b))[:10]
+		c = c[:5] + "-" + c[5:]
+		codes = append(codes, c)
+		hashes = append(hashes, hashRecoveryCode(c))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, so codes can be typed
+// however they were written down.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return hashToken(code)
+}
+
+// validTOTP is totp.Validate (30s steps, ±1 step of skew, 6 digits, SHA1)
+// against the server clock.
+func (s *Server) validTOTP(code, secret string) bool {
+	ok, _ := totp.ValidateCustom(code, secret, s.clock.now().UTC(), totp.ValidateOpts{
+		Period:    30,
+		Skew:      1,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+	return ok
+}
+
+// requireTOTPKey answers 503 when TOTP_ENCRYPTION_KEY is not configured.
+func (s *Server) requireTOTPKey(w http.ResponseWriter) bool {
+	if s.totpKey == nil {
+		writeError(w, http.StatusServiceUnavailable, codeTOTPUnavailable, "two-factor authentication is not configured on this server")
+		return false
+	}
+	return true
+}
+
+// checkSecondFactor reports whether code is the current TOTP code for the
+// sealed secret, or an unused recovery code of uid (which is then spent).
+func (s *Server) checkSecondFactor(uid int64, sealed, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if s.totpKey == nil {
+		return false, errors.New("user has 2FA enabled but TOTP_ENCRYPTION_KEY is not set")
+	}
+	secret, err := openSecret(s.totpKey, sealed)
+	if err != nil {
+		return false, err
+	}
+	if s.validTOTP(code, secret) {
+		return true, nil
+	}
+	res, err := s.db.Exec(
+		`UPDATE totp_recovery_codes SET used_at=$3 WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL`,
+		uid, hashRecoveryCode(code), s.clock.now(),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// POST /auth/2fa/setup
+// Generates a new secret (replacing any pending one) and returns
+// {secret, otpauthUri} for the authenticator app. 2FA stays off until
+// /auth/2fa/enable; 409 totp_already_enabled if it is already on.
+func (s *Server) setupTOTP(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+	if !s.requireTOTPKey(w) {
+		return
+	}
+
+	var email string
+	var enabled bool
+	if err := s.db.QueryRow(
+		`SELECT email, totp_enabled FROM users WHERE id=$1`, uid,
+	).Scan(&email, &enabled); err != nil {
+		serverError(w, err)
+		return
+	}
+	if enabled {
+		writeError(w, http.StatusConflict, codeTOTPEnabled, "two-factor authentication is already enabled")
+		return
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: totpIssuer, AccountName: email})
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	sealed, err := sealSecret(s.totpKey, key.Secret())
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if _, err := s.db.Exec(`UPDATE users SET totp_secret=$1 WHERE id=$2`, sealed, uid); err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"secret":     key.Secret(),
+		"otpauthUri": key.URL(),
+	})
+}
+
+// POST /auth/2fa/enable
+// {code} from the authenticator app. Switches 2FA on and returns
+// {recoveryCodes}; they are shown only this once. 400 invalid_totp_code if
+// the code doesn't match.
+func (s *Server) enableTOTP(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+	if !s.requireTOTPKey(w) {
+		return
+	}
+
+	var req totpCodeReq
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var sealed sql.NullString
+	var enabled bool
+	if err := tx.QueryRow(
+		`SELECT totp_secret, totp_enabled FROM users WHERE id=$1 FOR UPDATE`, uid,
+	).Scan(&sealed, &enabled); err != nil {
+		serverError(w, err)
+		return
+	}
+	if enabled {
+		writeError(w, http.StatusConflict, codeTOTPEnabled, "two-factor authentication is already enabled")
+		return
+	}
+	if !sealed.Valid {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "call /auth/2fa/setup first")
+		return
+	}
+	secret, err := openSecret(s.totpKey, sealed.String)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if !s.validTOTP(strings.TrimSpace(req.Code), secret) {
+		writeError(w, http.StatusBadRequest, codeInvalidTOTP, "invalid code")
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if _, err := tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id=$1`, uid); err != nil {
+		serverError(w, err)
+		return
+	}
+	for _, h := range hashes {
+		if _, err := tx.Exec(
+			`INSERT INTO totp_recovery_codes(user_id, code_hash) VALUES ($1,$2)`, uid, h,
+		); err != nil {
+			serverError(w, err)
+			return
+		}
+	}
+	if _, err := tx.Exec(`UPDATE users SET totp_enabled=true WHERE id=$1`, uid); err != nil {
+		serverError(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+	s.audit(r, uid, eventTOTPEnabled, nil)
+	writeJSON(w, http.StatusOK, map[string][]string{"recoveryCodes": codes})
+}
+
+// POST /auth/2fa/disable
+// {password}: turns 2FA off and drops the secret and recovery codes, 204.
+// 401 if the password is wrong.
+func (s *Server) disableTOTP(w http.ResponseWriter, r *http.Request) {
+	uid, _ := userIDFromCtx(r)
+
+	var req totpDisableReq
+	if err := decodeJSON(r, &req); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var hash string
+	if err := tx.QueryRow(`SELECT password_hash FROM users WHERE id=$1 FOR UPDATE`, uid).Scan(&hash); err != nil {
+		serverError(w, err)
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)) != nil {
+		writeError(w, http.StatusUnauthorized, codeInvalidCredentials, "invalid credentials")
+		return
+	}
+	for _, q := range []string{
+		`DELETE FROM totp_recovery_codes WHERE user_id=$1`,
+		`UPDATE users SET totp_secret=NULL, totp_enabled=false WHERE id=$1`,
+	} {
+		if _, err := tx.Exec(q, uid); err != nil {
+			serverError(w, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+	s.audit(r, uid, eventTOTPDisabled, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+//
+// ─────────────────────────────── Body validation ────────────────────────────
+//
+
+// FieldError is one invalid field of a request body. Code is stable
+// ("required", "invalid_email", or a password rule such as "min_length");
+// Message is for humans.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// fieldErrors collects every problem with a body, so a form can mark all
+// bad fields after one round trip instead of one per submit.
+type fieldErrors []FieldError
+
+func (fe *fieldErrors) add(field, code, message string) {
+	*fe = append(*fe, FieldError{field, code, message})
+}
+
+// required reports field when v is blank.
+func (fe *fieldErrors) required(field, v string) bool {
+	if strings.TrimSpace(v) == "" {
+		fe.add(field, "required", field+" is required")
+		return false
+	}
+	return true
+}
+
+// email reports field when v is missing or not an address (see validEmail).
+func (fe *fieldErrors) email(field, v string) {
+	if fe.required(field, v) && !validEmail(v) {
+		fe.add(field, codeInvalidEmail, field+" is not a valid email address")
+	}
+}
+
+// password reports every policy rule v breaks, one entry per rule.
+func (fe *fieldErrors) password(field, v string, policy PasswordPolicy) {
+	for _, f := range policy.Check(v) {
+		fe.add(field, f.Rule, field+" "+f.Message)
+	}
+}
+
+// validator is implemented by request bodies that check themselves.
+// Validate may normalize fields (e.g. the email) before checking them.
+type validator interface {
+	Validate(rules validationRules) fieldErrors
+}
+
+// validationRules is the server configuration Validate methods depend on.
+type validationRules struct {
+	password PasswordPolicy
+}
+
+// validationError carries the field errors of a rejected body; writeErr
+// reports it as 422 validation_failed with the list as details.
+type validationError struct {
+	fields fieldErrors
+}
+
+func (e *validationError) Error() string {
+	return "validation failed: " + e.fields[0].Message
+}
+
+// decodeValid is decodeJSON followed by v.Validate.
+func (s *Server) decodeValid(r *http.Request, v validator) error {
+	if err := decodeJSON(r, v); err != nil {
+		return err
+	}
+	if fe := v.Validate(validationRules{password: s.passwordPolicy}); len(fe) > 0 {
+		return &validationError{fe}
+	}
+	return nil
+}
+
+// writeValidation answers 422 if err is a *validationError.
+func writeValidation(w http.ResponseWriter, err error) bool {
+	var ve *validationError
+	if !errors.As(err, &ve) {
+		return false
+	}
+	writeErrorDetails(w, http.StatusUnprocessableEntity, codeValidationFailed,
+		"request has invalid fields", ve.fields)
+	return true
+}
+
+//
+// ───────────────────────────── Auth request bodies ──────────────────────────
+//
+
+// Validate normalizes the email; a malformed one is still reported, so a
+// typo isn't answered with a misleading 401.
+func (req *loginReq) Validate(validationRules) fieldErrors {
+	var fe fieldErrors
+	req.Email = normalizeEmail(req.Email)
+	fe.email("email", req.Email)
+	fe.required("password", req.Password)
+	return fe
+}
+
+func (req *registerReq) Validate(rules validationRules) fieldErrors {
+	var fe fieldErrors
+	req.Email = normalizeEmail(req.Email)
+	fe.email("email", req.Email)
+	fe.password("password", req.Password, rules.password)
+	return fe
+}
+
+func (req *changePasswordReq) Validate(rules validationRules) fieldErrors {
+	var fe fieldErrors
+	fe.required("oldPassword", req.OldPassword)
+	fe.password("newPassword", req.NewPassword, rules.password)
+	return fe
+}
+
+func (req *resetPasswordReq) Validate(rules validationRules) fieldErrors {
+	var fe fieldErrors
+	fe.required("token", req.Token)
+	fe.password("newPassword", req.NewPassword, rules.password)
+	return fe
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+//
+// ───────────────────────────── Email verification ───────────────────────────
+//
+
+const verificationTTL = 48 * time.Hour
+
+// newOpaqueToken returns a random URL-safe token for links sent by mail and
+// the hash we store; the plain token never touches the database.
+func newOpaqueToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createVerification stores a fresh verification token for uid. email is the
+// new address for an email change, or "" to confirm the current one.
+func createVerification(q execer, uid int64, email string, now time.Time) (string, error) {
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	if _, err := q.Exec(
+		`INSERT INTO email_verifications(token_hash, user_id, expires_at, email) VALUES ($1,$2,$3,NULLIF($4,''))`,
+		hash, uid, now.Add(verificationTTL), email,
+	); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// sendVerification mails the link for token. Failures are logged, not
+// returned: the account exists either way.
+func (s *Server) sendVerification(ctx context.Context, email, token string) {
+	link := s.publicURL + "/auth/verify?token=" + url.QueryEscape(token)
+	body := "Confirm your TimeTrac email address by opening this link:\n\n" + link +
+		"\n\nThe link expires in " + verificationTTL.String() + "."
+	if err := s.mailer.Send(ctx, email, "Confirm your email", body); err != nil {
+		log.Printf("send verification to %s: %v", email, err)
+	}
+}
+
+// GET /auth/verify?token=
+// Consumes a verification token and marks the email as verified. For an
+// email change this is when the pending address replaces the old one; 409 if
+// someone else registered it in the meantime.
+func (s *Server) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "token required")
+		return
+	}
+
+	tx, err := s.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var (
+		uid   int64
+		email sql.NullString
+	)
+	err = tx.QueryRow(`
+		UPDATE email_verifications SET used_at=$2
+		WHERE token_hash=$1 AND used_at IS NULL AND expires_at > $2
+		RETURNING user_id, email
+	`, hashToken(token), s.clock.now()).Scan(&uid, &email)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusBadRequest, codeInvalidToken, "invalid or expired token")
+		return
+	}
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	if email.Valid {
+		// Only the latest requested change counts; older links are stale.
+		res, err := tx.Exec(`
+			UPDATE users SET email=$2, pending_email=NULL, email_verified=true
+			WHERE id=$1 AND pending_email=$2
+		`, uid, email.String)
+		if isEmailTaken(err) {
+			writeError(w, http.StatusConflict, codeEmailTaken, "email already registered")
+			return
+		}
+		if err != nil {
+			serverError(w, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			writeError(w, http.StatusBadRequest, codeInvalidToken, "invalid or expired token")
+			return
+		}
+	} else if _, err := tx.Exec(`UPDATE users SET email_verified=true WHERE id=$1`, uid); err != nil {
+		serverError(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		serverError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "email verified"})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestVerificationExpiryFollowsClock(t *testing.T) {
+	e := newTestEnv(t)
+	uid, _ := e.user("alice@example.com")
+
+	fresh, err := createVerification(e.s.db, uid, "", e.clock.now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, err := createVerification(e.s.db, uid, "", e.clock.now().Add(-verificationTTL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Just expired by the server clock, though the database clock has long
+	// moved past both.
+	e.clock.advance(time.Second)
+	wantStatus(t, e.do("GET", "/auth/verify?token="+stale, "", nil), http.StatusBadRequest)
+	wantStatus(t, e.do("GET", "/auth/verify?token="+fresh, "", nil), http.StatusOK)
+
+	// The link was stamped used at the server's time, not the database's.
+	var usedAt time.Time
+	if err := e.s.db.QueryRow(`SELECT used_at FROM email_verifications WHERE user_id=$1 AND used_at IS NOT NULL`, uid).Scan(&usedAt); err != nil {
+		t.Fatal(err)
+	}
+	if !usedAt.Equal(e.clock.now()) {
+		t.Errorf("used_at = %v, want %v", usedAt, e.clock.now())
+	}
+}
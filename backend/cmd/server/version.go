@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+//
+// ────────────────────────────────── Version ─────────────────────────────────
+//
+
+// Build info, set at build time:
+//
+//	go build -ldflags "-X main.version=1.4.0 -X main.gitCommit=$(git rev-parse HEAD) \
+//	  -X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/server
+//
+// The Dockerfile passes them in as build args. Left empty, the commit and
+// time fall back to the VCS stamp Go embeds when building from a checkout.
+var (
+	version   = "dev"
+	gitCommit = ""
+	buildTime = ""
+)
+
+// BuildInfo is the response of GET /version.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+}
+
+// buildInfo is resolved once; it can't change while the process runs.
+var buildInfo = func() BuildInfo {
+	b := BuildInfo{Version: version, GitCommit: gitCommit, BuildTime: buildTime, GoVersion: runtime.Version()}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, kv := range info.Settings {
+			switch {
+			case kv.Key == "vcs.revision" && b.GitCommit == "":
+				b.GitCommit = kv.Value
+			case kv.Key == "vcs.time" && b.BuildTime == "":
+				b.BuildTime = kv.Value
+			}
+		}
+	}
+	return b
+}()
+
+// GET /version
+// Public and static: which build is running, for bug reports.
+func serveVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, buildInfo)
+}
@@ -102,7 +102,7 @@ export class HomePage implements OnInit, OnDestroy {
         this.refresh();
       },
       error: async e => (await this.toast.create({
-        message: e?.error || 'Already running?',
+        message: e?.error?.error?.message || 'Already running?',
         color: 'warning', duration: 1400, position: 'top'
       })).present(),
       complete: () => (this.loading = false),
@@ -118,7 +118,7 @@ export class HomePage implements OnInit, OnDestroy {
         this.refresh();
       },
       error: async e => (await this.toast.create({
-        message: e?.error || 'No open session',
+        message: e?.error?.error?.message || 'No open session',
         color: 'warning', duration: 1400, position: 'top'
       })).present(),
       complete: () => (this.loading = false),
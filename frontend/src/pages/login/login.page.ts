@@ -34,7 +34,7 @@ export class LoginPage {
         this.router.navigateByUrl('/home', { replaceUrl: true });
       },
       error: async (e: any) => {
-        const msg = e?.error?.message || e?.error || 'Login failed';
+        const msg = e?.error?.error?.message || 'Login failed';
         (await this.toast.create({ message: msg, color: 'danger', duration: 1500, position: 'top' })).present();
       },
       complete: () => (this.loading = false),
@@ -46,7 +46,7 @@ export class LoginPage {
     this.auth.register(this.email, this.password).subscribe({
       next: async () => (await this.toast.create({ message: 'Registered ✅', duration: 1000, position: 'top' })).present(),
       error: async (e: any) => {
-        const msg = e?.error?.message || e?.error || 'Register failed';
+        const msg = e?.error?.error?.message || 'Register failed';
         (await this.toast.create({ message: msg, color: 'danger', duration: 1500, position: 'top' })).present();
       }
     });
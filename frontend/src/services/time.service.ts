@@ -1,13 +1,16 @@
 import { Injectable } from '@angular/core';
 import { HttpClient } from '@angular/common/http';
+import { map } from 'rxjs';
 import { environment } from '../environments/environment';
 
 @Injectable({ providedIn: 'root' })
 export class TimeService {
   private base = environment.apiBase;
+  // Day boundaries follow the device's zone, not the server's.
+  private tz = { tz: Intl.DateTimeFormat().resolvedOptions().timeZone };
   constructor(private http: HttpClient) {}
   start()        { return this.http.post(`${this.base}/api/time/start`, {}); }
   stop()         { return this.http.post(`${this.base}/api/time/stop`, {}); }
-  sessionsToday(){ return this.http.get<any[]>(`${this.base}/api/time/sessions`); }
-  totalToday()   { return this.http.get<{ totalMinutes: number }>(`${this.base}/api/time/total-today`); }
+  sessionsToday(){ return this.http.get<{ items: any[] }>(`${this.base}/api/time/sessions`, { params: this.tz }).pipe(map(r => r.items)); }
+  totalToday()   { return this.http.get<{ totalMinutes: number }>(`${this.base}/api/time/total-today`, { params: this.tz }); }
 }